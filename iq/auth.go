@@ -0,0 +1,41 @@
+package privateiq
+
+import (
+	"net/http"
+)
+
+// WithUserToken authenticates with an IQ user token instead of the username and password of the public instance.
+// Both the private session and the requests to the public APIs use the token
+func WithUserToken(userCode, passCode string) Option {
+	return func(iq *privateiq) {
+		iq.Username = userCode
+		iq.Password = passCode
+		iq.sess = new(session)
+	}
+}
+
+// WithSessionCookie uses a session which was already established, such as through an SSO flow,
+// given as the cookies IQ set for it, including its CSRF token cookie.
+// The session cannot be established again, so requests fail with ErrUnauthorized once IQ expires it
+func WithSessionCookie(cookies ...*http.Cookie) Option {
+	return func(iq *privateiq) {
+		iq.Username = ""
+		iq.Password = ""
+		iq.sess = &session{established: true, injected: true, cookies: cookies}
+		for _, cookie := range cookies {
+			if cookie.Name == csrfCookie {
+				iq.sess.csrf = cookie.Value
+			}
+		}
+	}
+}
+
+// authenticate replaces the credentials of the public instance with those of the private one.
+// Requests are not given any credentials when the session was injected
+func (iq privateiq) authenticate(req *http.Request) {
+	if iq.Username == "" && iq.Password == "" {
+		req.Header.Del("Authorization")
+		return
+	}
+	req.SetBasicAuth(iq.Username, iq.Password)
+}
@@ -0,0 +1,69 @@
+package privateiq
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hokiegeek/gonexus-private/iq/privateiqtest"
+	nexusiq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+func TestUserToken(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	// The public instance has no valid credentials, as when IQ is fronted by SAML
+	pub, err := nexusiq.New(server.URL, "saml-user", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LicenseInfo(pub); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected the credentials to be rejected, got %v", err)
+	}
+
+	userCode, passCode := server.AddUserToken()
+	iq := FromPublic(pub, WithUserToken(userCode, passCode))
+
+	if _, err := LicenseInfo(iq); err != nil {
+		t.Fatalf("private API not authenticated with the user token: %v", err)
+	}
+	if err := CreateWebhook(iq, "http://localhost/hook", "", []string{"Application Evaluation"}); err != nil {
+		t.Fatalf("CSRF token not sent with the user token session: %v", err)
+	}
+	if _, err := nexusiq.GetAllOrganizations(iq); err != nil {
+		t.Fatalf("public API not authenticated with the user token: %v", err)
+	}
+}
+
+func TestSessionCookie(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	pub, err := nexusiq.New(server.URL, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	iq := FromPublic(pub, WithSessionCookie(server.NewSession()...))
+
+	if _, err := LicenseInfo(iq); err != nil {
+		t.Fatalf("injected session not used: %v", err)
+	}
+	if err := CreateWebhook(iq, "http://localhost/hook", "", []string{"Application Evaluation"}); err != nil {
+		t.Fatalf("CSRF token of the injected session not sent: %v", err)
+	}
+	if _, err := nexusiq.GetAllOrganizations(iq); err != nil {
+		t.Fatalf("public API not authenticated with the injected session: %v", err)
+	}
+
+	server.ExpireSessions()
+	if _, err := LicenseInfo(iq); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expected the expired session to be rejected, got %v", err)
+	}
+
+	for _, r := range server.Requests() {
+		if r == "GET "+restSessionPrivate {
+			t.Error("attempted to establish a session although one was injected")
+		}
+	}
+}
@@ -0,0 +1,414 @@
+package privateiq
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+// BackupFormatVersion is the version of the backup archives written by the package.
+// Archives of a newer version cannot be read
+const BackupFormatVersion = 1
+
+// Backup is the configuration of the organization hierarchy of an IQ server
+type Backup struct {
+	FormatVersion int       `json:"formatVersion"`
+	CreatedAt     time.Time `json:"createdAt"`
+	// Organizations are ordered so that parents come before their children. The Root Organization comes first
+	Organizations         []BackupOrganization   `json:"organizations"`
+	Applications          []BackupApplication    `json:"applications"`
+	Webhooks              []Webhook              `json:"webhooks"`
+	AutomaticApplications *AutomaticApplications `json:"automaticApplications,omitempty"`
+}
+
+// BackupOrganization is an organization along with its configuration
+type BackupOrganization struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	ParentID string `json:"parentId,omitempty"`
+	// Policies is the policy set as exported by IQ, kept verbatim
+	Policies        json.RawMessage    `json:"policies,omitempty"`
+	Labels          []IqComponentLabel `json:"labels,omitempty"`
+	MonitoringStage string             `json:"monitoringStage,omitempty"`
+}
+
+// BackupApplication is an application along with its configuration
+type BackupApplication struct {
+	ID              string `json:"id"`
+	PublicID        string `json:"publicId"`
+	Name            string `json:"name"`
+	OrganizationID  string `json:"organizationId"`
+	MonitoringStage string `json:"monitoringStage,omitempty"`
+}
+
+// AutomaticApplications is the configuration of the automatic creation of applications
+type AutomaticApplications struct {
+	Enabled              bool   `json:"enabled"`
+	ParentOrganizationID string `json:"parentOrganizationId"`
+}
+
+// WriteBackup writes the backup as an archive
+func WriteBackup(w io.Writer, backup *Backup) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(backup); err != nil {
+		return fmt.Errorf("could not write backup: %w", err)
+	}
+	return nil
+}
+
+// ReadBackup reads a backup archive written by WriteBackup
+func ReadBackup(r io.Reader) (*Backup, error) {
+	var backup Backup
+	if err := json.NewDecoder(r).Decode(&backup); err != nil {
+		return nil, fmt.Errorf("could not read backup: %w", err)
+	}
+	if backup.FormatVersion < 1 || backup.FormatVersion > BackupFormatVersion {
+		return nil, fmt.Errorf("backup format version %d is not supported", backup.FormatVersion)
+	}
+	return &backup, nil
+}
+
+// CreateBackup captures the organization tree, applications, policies, component labels,
+// continuous monitoring stages, automatic applications and webhooks of IQ
+func CreateBackup(iq publiciq.IQ) (*Backup, error) {
+	return CreateBackupContext(context.Background(), iq)
+}
+
+// CreateBackupContext captures the organization tree, applications, policies, component labels,
+// continuous monitoring stages, automatic applications and webhooks of IQ
+func CreateBackupContext(ctx context.Context, iq publiciq.IQ) (*Backup, error) {
+	piq := fromPublicContext(ctx, iq)
+
+	tree, err := GetOrganizationTreeContext(ctx, piq)
+	if err != nil {
+		return nil, err
+	}
+
+	backup := &Backup{FormatVersion: BackupFormatVersion, CreatedAt: time.Now().UTC()}
+
+	err = tree.Root.Walk(func(n *OrganizationNode) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		org := BackupOrganization{ID: n.ID, Name: n.Name}
+		if n.Parent != nil {
+			org.ParentID = n.Parent.ID
+		}
+
+		endpoint, err := piq.endpoint("ExportPolicies", n.ID)
+		if err != nil {
+			return err
+		}
+		if org.Policies, _, err = piq.Get(endpoint); err != nil {
+			return fmt.Errorf("could not export policies of organization '%s': %w", n.Name, err)
+		}
+
+		if org.Labels, err = getComponentLabels(piq, n.ID); err != nil {
+			return err
+		}
+		if org.MonitoringStage, err = getMonitoringStage(piq, "EnableContinuousMonitoringOrganization", n.ID); err != nil {
+			return err
+		}
+		backup.Organizations = append(backup.Organizations, org)
+
+		for _, a := range n.Applications {
+			app := BackupApplication{ID: a.ID, PublicID: a.PublicID, Name: a.Name, OrganizationID: n.ID}
+			if app.MonitoringStage, err = getMonitoringStage(piq, "EnableContinuousMonitoringApplication", a.ID); err != nil {
+				return err
+			}
+			backup.Applications = append(backup.Applications, app)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if backup.Webhooks, err = getWebhooks(piq); err != nil {
+		return nil, err
+	}
+
+	endpoint, err := piq.endpoint("GetAutomaticApplications")
+	if err != nil {
+		return nil, err
+	}
+	body, _, err := piq.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve automatic applications: %w", err)
+	}
+	backup.AutomaticApplications = new(AutomaticApplications)
+	if err = json.Unmarshal(body, backup.AutomaticApplications); err != nil {
+		return nil, fmt.Errorf("could not parse automatic applications: %w", err)
+	}
+
+	return backup, nil
+}
+
+func getWebhooks(iq *privateiq) ([]Webhook, error) {
+	endpoint, err := iq.endpoint("GetWebhooks")
+	if err != nil {
+		return nil, err
+	}
+
+	body, _, err := iq.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve webhooks: %w", err)
+	}
+
+	var webhooks []Webhook
+	if err = json.Unmarshal(body, &webhooks); err != nil {
+		return nil, fmt.Errorf("could not parse webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// RestoreConflict is an item of a backup which already exists on the server it is restored into.
+// The existing item is kept as is
+type RestoreConflict struct {
+	// Kind is one of organization, application, label or webhook
+	Kind   string
+	Name   string
+	Reason string
+}
+
+// RestoreReport is the outcome of restoring a backup
+type RestoreReport struct {
+	// IDs maps the IDs of the organizations and applications of the backup to their IDs on the server
+	IDs       map[string]string
+	Created   int
+	Conflicts []RestoreConflict
+}
+
+func (r *RestoreReport) conflict(kind, name, reason string) {
+	r.Conflicts = append(r.Conflicts, RestoreConflict{Kind: kind, Name: name, Reason: reason})
+}
+
+// RestoreBackup replays the backup into IQ, creating what does not exist yet. Items which exist already,
+// organizations with the same name under the same parent and applications with the same public ID, are kept
+// and reported as conflicts. Their configuration, such as policies and monitoring, is still restored.
+// The report is returned even when the restore stops because of an error
+func RestoreBackup(iq publiciq.IQ, backup *Backup) (*RestoreReport, error) {
+	return RestoreBackupContext(context.Background(), iq, backup)
+}
+
+// RestoreBackupContext replays the backup into IQ, creating what does not exist yet. Items which exist already,
+// organizations with the same name under the same parent and applications with the same public ID, are kept
+// and reported as conflicts. Their configuration, such as policies and monitoring, is still restored.
+// The report is returned even when the restore stops because of an error
+func RestoreBackupContext(ctx context.Context, iq publiciq.IQ, backup *Backup) (*RestoreReport, error) {
+	piq := fromPublicContext(ctx, iq)
+	report := &RestoreReport{IDs: map[string]string{publiciq.RootOrganization: publiciq.RootOrganization}}
+
+	tree, err := GetOrganizationTreeContext(ctx, piq)
+	if err != nil {
+		return report, err
+	}
+
+	for _, o := range backup.Organizations {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		if err := restoreOrganization(piq, tree, o, report); err != nil {
+			return report, err
+		}
+	}
+
+	existing := make(map[string]publiciq.Application)
+	apps, err := getAllApplications(piq)
+	if err != nil {
+		return report, err
+	}
+	for _, a := range apps {
+		existing[a.PublicID] = a
+	}
+
+	for _, a := range backup.Applications {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		if err := restoreApplication(piq, existing, a, report); err != nil {
+			return report, err
+		}
+	}
+
+	if err := restoreWebhooks(piq, backup.Webhooks, report); err != nil {
+		return report, err
+	}
+
+	if backup.AutomaticApplications != nil {
+		settings := *backup.AutomaticApplications
+		settings.ParentOrganizationID = report.IDs[settings.ParentOrganizationID]
+		if err := putJSON(piq, "EnableAutomaticApplications", settings); err != nil {
+			return report, fmt.Errorf("could not restore automatic applications: %w", err)
+		}
+	}
+
+	return report, nil
+}
+
+func restoreOrganization(piq *privateiq, tree *OrganizationTree, o BackupOrganization, report *RestoreReport) error {
+	if o.ID == publiciq.RootOrganization || o.ParentID == "" {
+		report.IDs[o.ID] = publiciq.RootOrganization
+	} else {
+		parentID, ok := report.IDs[o.ParentID]
+		if !ok {
+			return fmt.Errorf("parent '%s' of organization '%s': %w", o.ParentID, o.Name, ErrNotFound)
+		}
+
+		var id string
+		if parent, ok := tree.Find(parentID); ok {
+			for _, c := range parent.Children {
+				if c.Name == o.Name {
+					id = c.ID
+				}
+			}
+		}
+
+		if id != "" {
+			report.conflict("organization", o.Name, "an organization with the same name already exists under the same parent")
+		} else {
+			var err error
+			if id, err = createOrganization(piq, o.Name, parentID); err != nil {
+				return err
+			}
+			report.Created++
+		}
+		report.IDs[o.ID] = id
+	}
+	id := report.IDs[o.ID]
+
+	if len(o.Policies) > 0 {
+		if err := importPolicies(piq, id, bytes.NewReader(o.Policies)); err != nil {
+			return fmt.Errorf("could not restore policies of organization '%s': %w", o.Name, err)
+		}
+	}
+
+	labels, err := getComponentLabels(piq, id)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool, len(labels))
+	for _, l := range labels {
+		existing[l.LabelLowercase] = true
+	}
+	for _, l := range o.Labels {
+		if existing[l.LabelLowercase] {
+			report.conflict("label", l.Label, fmt.Sprintf("organization '%s' already has the label", o.Name))
+			continue
+		}
+		label := IqComponentLabel{OwnerID: id, Label: l.Label, Description: l.Description, Color: l.Color}
+		if err := postJSON(piq, "CreateComponentLabel", label, id); err != nil {
+			return fmt.Errorf("could not restore label '%s' of organization '%s': %w", l.Label, o.Name, err)
+		}
+		report.Created++
+	}
+
+	if o.MonitoringStage != "" {
+		if err := putJSON(piq, "EnableContinuousMonitoringOrganization", policyMonitoringRequest{o.MonitoringStage}, id); err != nil {
+			return fmt.Errorf("could not restore monitoring of organization '%s': %w", o.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func restoreApplication(piq *privateiq, existing map[string]publiciq.Application, a BackupApplication, report *RestoreReport) error {
+	orgID, ok := report.IDs[a.OrganizationID]
+	if !ok {
+		return fmt.Errorf("organization '%s' of application '%s': %w", a.OrganizationID, a.PublicID, ErrNotFound)
+	}
+
+	if app, ok := existing[a.PublicID]; ok {
+		reason := "an application with the same public ID already exists"
+		if app.OrganizationID != orgID {
+			reason += " in another organization"
+		}
+		report.conflict("application", a.PublicID, reason)
+		report.IDs[a.ID] = app.ID
+	} else {
+		id, err := createApplication(piq, a.Name, a.PublicID, orgID)
+		if err != nil {
+			return err
+		}
+		report.IDs[a.ID] = id
+		report.Created++
+	}
+
+	if a.MonitoringStage != "" {
+		if err := putJSON(piq, "EnableContinuousMonitoringApplication", policyMonitoringRequest{a.MonitoringStage}, report.IDs[a.ID]); err != nil {
+			return fmt.Errorf("could not restore monitoring of application '%s': %w", a.PublicID, err)
+		}
+	}
+
+	return nil
+}
+
+func restoreWebhooks(piq *privateiq, webhooks []Webhook, report *RestoreReport) error {
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	current, err := getWebhooks(piq)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool, len(current))
+	for _, w := range current {
+		existing[w.URL] = true
+	}
+
+	for _, w := range webhooks {
+		if existing[w.URL] {
+			report.conflict("webhook", w.URL, "a webhook with the same URL already exists")
+			continue
+		}
+		w.ID = ""
+		if err := postJSON(piq, "CreateWebhook", w); err != nil {
+			return fmt.Errorf("could not restore webhook '%s': %w", w.URL, err)
+		}
+		report.Created++
+	}
+
+	return nil
+}
+
+// putJSON sends the payload to the endpoint used by the named function
+func putJSON(piq *privateiq, function string, payload interface{}, args ...interface{}) error {
+	endpoint, err := piq.endpoint(function, args...)
+	if err != nil {
+		return err
+	}
+
+	buf, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = piq.Put(endpoint, bytes.NewBuffer(buf))
+	return err
+}
+
+// postJSON posts the payload to the endpoint used by the named function
+func postJSON(piq *privateiq, function string, payload interface{}, args ...interface{}) error {
+	endpoint, err := piq.endpoint(function, args...)
+	if err != nil {
+		return err
+	}
+
+	buf, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = piq.Post(endpoint, bytes.NewBuffer(buf))
+	return err
+}
@@ -0,0 +1,116 @@
+package privateiq
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hokiegeek/gonexus-private/iq/privateiqtest"
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+var backupTestPolicies = json.RawMessage(`{"policies":[{"name":"Security-High"}],"licenseThreatGroups":[],"licenseThreatGroupLicenses":[],"labels":[],"policyTags":[],"tags":[]}`)
+
+func TestBackupRestore(t *testing.T) {
+	source := privateiqtest.NewServer()
+	defer source.Close()
+
+	unit := source.AddOrganization("unit", "")
+	team := source.AddOrganization("team", unit.ID)
+	source.AddApplication("teamapp", "teamapp", team.ID)
+	source.AddApplication("shared", "shared", unit.ID)
+	source.AddComponentLabel(team.ID, privateiqtest.ComponentLabel{Label: "Foo", Color: "orange"})
+	source.SetPolicies(team.ID, backupTestPolicies)
+	if err := EnableContinuousMonitoringApplication(source.IQ(), "teamapp", publiciq.StageRelease); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnableAutomaticApplications(source.IQ(), "team"); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateWebhook(source.IQ(), "http://example.com/hook", "secret", []string{WebhookEventAppEval}); err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := CreateBackup(source.IQ())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteBackup(&buf, backup); err != nil {
+		t.Fatal(err)
+	}
+	if backup, err = ReadBackup(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	target := privateiqtest.NewServer()
+	defer target.Close()
+
+	// The existing organization and application are kept, and reported
+	existingUnit := target.AddOrganization("unit", "")
+	target.AddApplication("shared", "shared", existingUnit.ID)
+
+	report, err := RestoreBackup(target.IQ(), backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.IDs[unit.ID] != existingUnit.ID {
+		t.Errorf("organization 'unit' was mapped to %q, want the existing %q", report.IDs[unit.ID], existingUnit.ID)
+	}
+	if len(report.Conflicts) != 2 || report.Conflicts[0].Kind != "organization" || report.Conflicts[1].Kind != "application" {
+		t.Errorf("unexpected conflicts: %v", report.Conflicts)
+	}
+
+	newTeam := report.IDs[team.ID]
+	var found bool
+	for _, o := range target.Organizations() {
+		if o.ID == newTeam && o.Name == "team" && o.ParentOrganizationID == existingUnit.ID {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("organization 'team' was not restored under 'unit': %v", target.Organizations())
+	}
+
+	var policies bytes.Buffer
+	if err := json.Compact(&policies, target.Policies(newTeam)); err != nil || !bytes.Equal(policies.Bytes(), backupTestPolicies) {
+		t.Errorf("policies were not restored: %s", target.Policies(newTeam))
+	}
+	if labels := target.ComponentLabels(); len(labels) != 1 || labels[0].OwnerID != newTeam || labels[0].Label != "Foo" {
+		t.Errorf("labels were not restored: %v", labels)
+	}
+
+	var teamapp string
+	for _, a := range target.Applications() {
+		if a.PublicID == "teamapp" {
+			teamapp = a.ID
+		}
+	}
+	if stage, _ := target.Monitoring(teamapp); stage != publiciq.StageRelease {
+		t.Errorf("application monitors %q, want %q", stage, publiciq.StageRelease)
+	}
+	if got := target.AutomaticApplications(); !got.Enabled || got.ParentOrganizationID != newTeam {
+		t.Errorf("automatic applications were not restored: %v", got)
+	}
+	if hooks := target.Webhooks(); len(hooks) != 1 || hooks[0].URL != "http://example.com/hook" {
+		t.Errorf("webhooks were not restored: %v", hooks)
+	}
+
+	// Restoring again only conflicts
+	report, err = RestoreBackup(target.IQ(), backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Created != 0 {
+		t.Errorf("restoring twice created %d items", report.Created)
+	}
+}
+
+func TestReadBackupNewerFormat(t *testing.T) {
+	if _, err := ReadBackup(strings.NewReader(`{"formatVersion":2}`)); err == nil {
+		t.Error("reading a newer backup format did not fail")
+	}
+}
@@ -0,0 +1,153 @@
+package privateiq
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+// BatchOptions configures how EvaluateComponentsBatch splits and evaluates a list of components
+type BatchOptions struct {
+	// Organization is the ID or name of the organization whose policies the components are evaluated with
+	Organization string
+	// ChunkSize is the number of components evaluated by each request
+	ChunkSize int
+	// Parallelism is the number of chunks evaluated at once, which the limiter of the instance may lower
+	Parallelism int
+}
+
+// DefaultBatchOptions evaluates chunks of 500 components, 4 at a time, with the policies of the Root Organization
+var DefaultBatchOptions = BatchOptions{
+	Organization: publiciq.RootOrganization,
+	ChunkSize:    500,
+	Parallelism:  4,
+}
+
+// ChunkError is the failure to evaluate a chunk of a batch
+type ChunkError struct {
+	// Start and End delimit the chunk in the list of components given to EvaluateComponentsBatch
+	Start, End int
+	Err        error
+}
+
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("components %d to %d not evaluated: %v", e.Start, e.End-1, e.Err)
+}
+
+// Unwrap returns the reason the chunk failed
+func (e *ChunkError) Unwrap() error {
+	return e.Err
+}
+
+// BatchEvaluation is the evaluation of every chunk of a batch merged together.
+// Its results are in the order of the components given, skipping those of the chunks which failed.
+// Its ApplicationID is left empty, as the temporary application is deleted once done
+type BatchEvaluation struct {
+	publiciq.Evaluation
+	Chunks int
+	// Errors lists the chunks which failed, in order
+	Errors []*ChunkError
+}
+
+// EvaluateComponentsBatch evaluates a list of components too large for a single request, by splitting it into chunks
+// evaluated in parallel by the same temporary application. Whatever was evaluated is returned, even when chunks failed,
+// in which case the error wraps the first failure
+func EvaluateComponentsBatch(iq publiciq.IQ, components []publiciq.Component, opts BatchOptions) (*BatchEvaluation, error) {
+	return EvaluateComponentsBatchContext(context.Background(), iq, components, opts)
+}
+
+// EvaluateComponentsBatchContext evaluates a list of components too large for a single request, by splitting it into chunks
+// evaluated in parallel by the same temporary application. Whatever was evaluated is returned, even when chunks failed,
+// in which case the error wraps the first failure
+func EvaluateComponentsBatchContext(ctx context.Context, iq publiciq.IQ, components []publiciq.Component, opts BatchOptions) (batch *BatchEvaluation, err error) {
+	piq := fromPublicContext(ctx, iq)
+
+	if opts.Organization == "" {
+		opts.Organization = DefaultBatchOptions.Organization
+	}
+	if opts.ChunkSize < 1 {
+		opts.ChunkSize = DefaultBatchOptions.ChunkSize
+	}
+	if opts.Parallelism < 1 {
+		opts.Parallelism = DefaultBatchOptions.Parallelism
+	}
+
+	if len(components) == 0 {
+		return &BatchEvaluation{Evaluation: publiciq.Evaluation{Results: make([]publiciq.ComponentEvaluationResult, 0)}}, nil
+	}
+
+	parentID, err := getOrganizationID(piq, opts.Organization)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := createTempApplication(ctx, piq, parentID)
+	defer func() {
+		err = tmp.cleanup(err)
+	}()
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make([]*ChunkError, 0, len(components)/opts.ChunkSize+1)
+	for start := 0; start < len(components); start += opts.ChunkSize {
+		end := start + opts.ChunkSize
+		if end > len(components) {
+			end = len(components)
+		}
+		chunks = append(chunks, &ChunkError{Start: start, End: end})
+	}
+	evals := make([]*publiciq.Evaluation, len(chunks))
+
+	var wg sync.WaitGroup
+	indexes := make(chan int)
+	for w := piq.limiter.workers(opts.Parallelism); w > 0; w-- {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				c := chunks[i]
+				if c.Err = ctx.Err(); c.Err == nil {
+					evals[i], c.Err = evaluateComponents(ctx, piq, components[c.Start:c.End], tmp.appID)
+				}
+			}
+		}()
+	}
+	for i := range chunks {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	batch = &BatchEvaluation{Chunks: len(chunks)}
+	batch.Results = make([]publiciq.ComponentEvaluationResult, 0, len(components))
+	for i, eval := range evals {
+		if chunks[i].Err != nil {
+			batch.Errors = append(batch.Errors, chunks[i])
+			continue
+		}
+		batch.merge(eval)
+	}
+
+	if len(batch.Errors) > 0 {
+		return batch, fmt.Errorf("%d of %d chunks not evaluated, the first because: %w", len(batch.Errors), len(chunks), batch.Errors[0])
+	}
+
+	return batch, nil
+}
+
+// merge adds the evaluation of a chunk. The dates span every chunk, which IQ formats so that they sort as strings
+func (b *BatchEvaluation) merge(eval *publiciq.Evaluation) {
+	if b.SubmittedDate == "" || eval.SubmittedDate < b.SubmittedDate {
+		b.SubmittedDate = eval.SubmittedDate
+	}
+	if eval.EvaluationDate > b.EvaluationDate {
+		b.EvaluationDate = eval.EvaluationDate
+	}
+	if eval.IsError && !b.IsError {
+		b.IsError, b.ErrorMessage = true, eval.ErrorMessage
+	}
+	b.Results = append(b.Results, eval.Results...)
+}
@@ -0,0 +1,90 @@
+package privateiq
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hokiegeek/gonexus-private/iq/privateiqtest"
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+// failingNth is a transport which fails the nth evaluation request
+type failingNth struct {
+	n, count int32
+}
+
+func (f *failingNth) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodPost && strings.HasPrefix(req.URL.Path, "/api/v2/evaluation/") && atomic.AddInt32(&f.count, 1) == f.n {
+		return &http.Response{
+			StatusCode: http.StatusRequestEntityTooLarge,
+			Header:     make(http.Header),
+			Body:       ioutil.NopCloser(strings.NewReader("too many components")),
+			Request:    req,
+		}, nil
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestEvaluateComponentsBatch(t *testing.T) {
+	evaluationPollInterval = time.Millisecond
+
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	components := make([]publiciq.Component, 30)
+	for i := range components {
+		components[i].Hash = fmt.Sprintf("hash%02d", i)
+	}
+	server.AddKnownComponent(privateiqtest.KnownComponent{Component: components[3]})
+
+	opts := BatchOptions{ChunkSize: 10, Parallelism: 3}
+
+	batch, err := EvaluateComponentsBatch(server.IQ(), components, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.Chunks != 3 || len(batch.Results) != len(components) {
+		t.Fatalf("got %d results in %d chunks", len(batch.Results), batch.Chunks)
+	}
+	for i, r := range batch.Results {
+		if r.Component.Hash != components[i].Hash {
+			t.Errorf("result %d is for %s, want %s", i, r.Component.Hash, components[i].Hash)
+		}
+	}
+	if batch.Results[3].MatchState != "exact" {
+		t.Errorf("known component matched %q", batch.Results[3].MatchState)
+	}
+
+	// A failed chunk is reported, and the others are still merged
+	iq := FromPublic(server.IQ(), WithTransport(&failingNth{n: 2}))
+	batch, err = EvaluateComponentsBatch(iq, components, opts)
+	var chunkErr *ChunkError
+	if !errors.As(err, &chunkErr) || len(batch.Errors) != 1 || batch.Errors[0] != chunkErr {
+		t.Fatalf("got error %v, want one *ChunkError", err)
+	}
+	if chunkErr.End-chunkErr.Start != 10 || len(batch.Results) != 20 {
+		t.Errorf("failed chunk is %d to %d, with %d results", chunkErr.Start, chunkErr.End, len(batch.Results))
+	}
+
+	if orgs := server.Organizations(); len(orgs) != 1 {
+		t.Errorf("temporary organization was not deleted: %v", orgs)
+	}
+	if batch.ApplicationID != "" {
+		t.Errorf("the batch refers to the deleted application %s", batch.ApplicationID)
+	}
+
+	// Nothing is created to evaluate no components
+	requests := len(server.Requests())
+	if batch, err = EvaluateComponentsBatch(server.IQ(), nil, opts); err != nil || len(batch.Results) != 0 || batch.Chunks != 0 {
+		t.Errorf("evaluating no components returned %v, %v", batch, err)
+	}
+	if len(server.Requests()) != requests {
+		t.Errorf("evaluating no components sent %v", server.Requests()[requests:])
+	}
+}
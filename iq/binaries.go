@@ -0,0 +1,172 @@
+package privateiq
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+// DefaultBinaryExtensions are the files hashed by EvaluateBinaries unless told otherwise:
+// the archives and libraries IQ identifies components by
+var DefaultBinaryExtensions = []string{
+	".jar", ".war", ".ear", ".aar", ".hpi",
+	".whl", ".egg", ".tar.gz", ".tgz", ".zip",
+	".gem", ".nupkg", ".dll", ".exe", ".so",
+}
+
+// BinaryResult is the evaluation of a file
+type BinaryResult struct {
+	// Path is relative to the directory evaluated
+	Path string
+	SHA1 string
+	// MatchState is how IQ matched the file, which is "unknown" when it could not identify it
+	// or returned no result for it
+	MatchState string
+	// Component is the component IQ identified the file as
+	Component  publiciq.Component
+	Violations []publiciq.PolicyViolation
+}
+
+// Identified reports whether IQ recognized the file as a component
+func (r BinaryResult) Identified() bool {
+	return r.MatchState != "" && r.MatchState != "unknown"
+}
+
+// BinaryEvaluation is the evaluation of the files of a directory
+type BinaryEvaluation struct {
+	Evaluation *publiciq.Evaluation
+	// Files are sorted by path. Identical files are only evaluated once
+	Files []BinaryResult
+}
+
+// hashBinaries returns the files under the directory which have one of the extensions, with their SHA-1 hash
+func hashBinaries(ctx context.Context, dir string, extensions []string) ([]BinaryResult, error) {
+	files := make([]BinaryResult, 0)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() || !hasExtension(info.Name(), extensions) {
+			return nil
+		}
+
+		hash, err := sha1File(path)
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, BinaryResult{Path: filepath.ToSlash(rel), SHA1: hash})
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not hash the files of '%s': %w", dir, err)
+	}
+
+	return files, nil
+}
+
+func hasExtension(name string, extensions []string) bool {
+	name = strings.ToLower(name)
+	for _, ext := range extensions {
+		if strings.HasSuffix(name, strings.ToLower(ext)) {
+			return true
+		}
+	}
+	return false
+}
+
+func sha1File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha1.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// EvaluateBinaries evaluates the files under the directory by their SHA-1 hash, using Root Organization only.
+// Only the files with one of the given extensions, or DefaultBinaryExtensions if none are given, are evaluated
+func EvaluateBinaries(iq publiciq.IQ, dir string, extensions ...string) (*BinaryEvaluation, error) {
+	return EvaluateBinariesContext(context.Background(), iq, dir, extensions...)
+}
+
+// EvaluateBinariesContext evaluates the files under the directory by their SHA-1 hash, using Root Organization only.
+// Only the files with one of the given extensions, or DefaultBinaryExtensions if none are given, are evaluated
+func EvaluateBinariesContext(ctx context.Context, iq publiciq.IQ, dir string, extensions ...string) (*BinaryEvaluation, error) {
+	if len(extensions) == 0 {
+		extensions = DefaultBinaryExtensions
+	}
+
+	files, err := hashBinaries(ctx, dir, extensions)
+	if err != nil {
+		return nil, err
+	}
+
+	components := make([]publiciq.Component, 0, len(files))
+	seen := make(map[string]bool)
+	for _, f := range files {
+		if !seen[f.SHA1] {
+			seen[f.SHA1] = true
+			components = append(components, publiciq.Component{Hash: f.SHA1})
+		}
+	}
+
+	eval := &BinaryEvaluation{Files: files}
+	if len(components) == 0 {
+		eval.Evaluation = &publiciq.Evaluation{Results: make([]publiciq.ComponentEvaluationResult, 0)}
+		return eval, nil
+	}
+
+	if eval.Evaluation, err = EvaluateComponentsWithRootOrgContext(ctx, iq, components); err != nil {
+		return eval, err
+	}
+
+	// Results are matched by hash rather than by position, as IQ does not promise to keep the order.
+	// IQ may only answer with the start of the hash, so files are looked up by prefixes of every length answered
+	results := make(map[string]publiciq.ComponentEvaluationResult, len(eval.Evaluation.Results))
+	lengths := make(map[int]bool)
+	for _, r := range eval.Evaluation.Results {
+		if hash := strings.ToLower(r.Component.Hash); hash != "" {
+			results[hash] = r
+			lengths[len(hash)] = true
+		}
+	}
+	for i := range eval.Files {
+		f := &eval.Files[i]
+		f.MatchState = "unknown"
+		for n := range lengths {
+			if n > len(f.SHA1) {
+				continue
+			}
+			if r, ok := results[f.SHA1[:n]]; ok {
+				f.MatchState = r.MatchState
+				f.Component = r.Component
+				f.Violations = r.PolicyData.PolicyViolations
+				break
+			}
+		}
+	}
+
+	return eval, nil
+}
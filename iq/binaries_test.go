@@ -0,0 +1,152 @@
+package privateiq
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hokiegeek/gonexus-private/iq/privateiqtest"
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+func binariesTestDir(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "binaries")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range map[string]string{
+		"lib/known.jar":          "known",
+		"lib/unknown.jar":        "unknown",
+		"vendor/copy/known.JAR":  "known",
+		"wheels/dummy-1.0.0.whl": "wheel",
+		"README.md":              "not a binary",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func binariesTestServer() *privateiqtest.Server {
+	server := privateiqtest.NewServer()
+
+	// The SHA-1 hash of "known"
+	known := publiciq.Component{Hash: "6ca33ad04dbc4e215660f7707ece0fb2c03aeace"}
+	violation := publiciq.PolicyViolation{PolicyName: "Security-High", ThreatLevel: 9}
+	server.AddKnownComponent(privateiqtest.KnownComponent{Component: known, Violations: []publiciq.PolicyViolation{violation}})
+
+	return server
+}
+
+func TestEvaluateBinaries(t *testing.T) {
+	evaluationPollInterval = time.Millisecond
+
+	dir := binariesTestDir(t)
+	defer os.RemoveAll(dir)
+
+	server := binariesTestServer()
+	defer server.Close()
+
+	eval, err := EvaluateBinaries(server.IQ(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(eval.Evaluation.Results) != 3 {
+		t.Errorf("evaluated %d components, want 3", len(eval.Evaluation.Results))
+	}
+	want := map[string]bool{"lib/known.jar": true, "lib/unknown.jar": false, "vendor/copy/known.JAR": true, "wheels/dummy-1.0.0.whl": false}
+	if len(eval.Files) != len(want) {
+		t.Fatalf("got files %v", eval.Files)
+	}
+	for _, f := range eval.Files {
+		identified, ok := want[f.Path]
+		if !ok || f.Identified() != identified {
+			t.Errorf("%s identified: %t", f.Path, f.Identified())
+		}
+		if identified && (len(f.Violations) != 1 || f.Violations[0].PolicyName != "Security-High") {
+			t.Errorf("%s violates %v", f.Path, f.Violations)
+		}
+	}
+
+	// Extensions narrow down the files evaluated
+	if eval, err = EvaluateBinaries(server.IQ(), dir, ".whl"); err != nil {
+		t.Fatal(err)
+	}
+	if len(eval.Files) != 1 || eval.Files[0].Path != "wheels/dummy-1.0.0.whl" || eval.Files[0].MatchState != "unknown" {
+		t.Errorf("got files %v", eval.Files)
+	}
+}
+
+// truncating is a transport which shuffles the results of evaluations and only keeps the start of their hashes
+type truncating struct{}
+
+func (truncating) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := shuffling{}.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK || !strings.Contains(req.URL.Path, "/results/") {
+		return resp, err
+	}
+	defer resp.Body.Close()
+
+	var eval struct {
+		Results []map[string]interface{} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&eval); err != nil {
+		return nil, err
+	}
+	for _, r := range eval.Results {
+		component := r["component"].(map[string]interface{})
+		component["hash"] = component["hash"].(string)[:20]
+	}
+	buf, _ := json.Marshal(eval)
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(buf))
+	resp.ContentLength = int64(len(buf))
+	return resp, nil
+}
+
+func TestEvaluateBinariesMatchesResults(t *testing.T) {
+	evaluationPollInterval = time.Millisecond
+
+	dir := binariesTestDir(t)
+	defer os.RemoveAll(dir)
+
+	server := binariesTestServer()
+	defer server.Close()
+
+	eval, err := EvaluateBinaries(FromPublic(server.IQ(), WithTransport(truncating{})), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(eval.Evaluation.Results) != 2 {
+		t.Errorf("got %d results, want 2", len(eval.Evaluation.Results))
+	}
+	for _, f := range eval.Files {
+		known := strings.HasSuffix(strings.ToLower(f.Path), "known.jar") && !strings.HasSuffix(f.Path, "unknown.jar")
+		if f.Identified() != known {
+			t.Errorf("%s identified: %t", f.Path, f.Identified())
+		}
+		if known != (len(f.Violations) == 1) {
+			t.Errorf("%s violates %v", f.Path, f.Violations)
+		}
+		if !known && f.MatchState != "unknown" {
+			t.Errorf("%s matched %q", f.Path, f.MatchState)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package privateiq
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// redacted replaces every secret written to a cassette
+const redacted = "REDACTED"
+
+// Headers whose values are always secret
+var secretHeaders = []string{"Authorization", "Proxy-Authorization", csrfHeader}
+
+// Matches the value of JSON fields which hold passwords, tokens and other secrets
+var secretJSONField = regexp.MustCompile(`(?i)("[^"]*(?:password|passcode|secret|token)[^"]*"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// Interaction is a request and the response IQ gave to it
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a request, scrubbed of its secrets
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URI    string      `json:"uri"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+	// Encoding is "base64" when the body is binary
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// RecordedResponse is a response, scrubbed of its secrets
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	// Encoding is "base64" when the body is binary
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// Cassette is the list of interactions stored in a fixture file
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// WithTransport sets the transport through which every request is sent, such as a Recorder or a Replayer
+func WithTransport(transport http.RoundTripper) Option {
+	return func(iq *privateiq) {
+		client := *iq.client
+		client.Transport = transport
+		iq.client = &client
+	}
+}
+
+func scrubHeader(header http.Header) http.Header {
+	scrubbed := make(http.Header, len(header))
+	for k, v := range header {
+		scrubbed[k] = append([]string(nil), v...)
+	}
+
+	for _, h := range secretHeaders {
+		if scrubbed.Get(h) != "" {
+			scrubbed.Set(h, redacted)
+		}
+	}
+
+	// Keep the names of the cookies, which tells which session cookies IQ used
+	if cookies := scrubbed["Cookie"]; len(cookies) > 0 {
+		req := http.Request{Header: http.Header{"Cookie": cookies}}
+		names := make([]string, 0)
+		for _, c := range req.Cookies() {
+			names = append(names, c.Name+"="+redacted)
+		}
+		scrubbed["Cookie"] = []string{strings.Join(names, "; ")}
+	}
+	for i, c := range scrubbed["Set-Cookie"] {
+		resp := http.Response{Header: http.Header{"Set-Cookie": []string{c}}}
+		for _, cookie := range resp.Cookies() {
+			cookie.Value = redacted
+			scrubbed["Set-Cookie"][i] = cookie.String()
+		}
+	}
+
+	return scrubbed
+}
+
+// scrubBody returns the body as text with its secrets scrubbed, or base64 encoded if it is binary
+func scrubBody(body []byte) (string, string) {
+	if !utf8.Valid(body) {
+		return base64.StdEncoding.EncodeToString(body), "base64"
+	}
+	return secretJSONField.ReplaceAllString(string(body), `$1"`+redacted+`"`), ""
+}
+
+func decodeBody(body, encoding string) ([]byte, error) {
+	if encoding == "base64" {
+		return base64.StdEncoding.DecodeString(body)
+	}
+	return []byte(body), nil
+}
+
+// Recorder is an http.RoundTripper which records every request sent through it, and its response, into a cassette.
+// Cookies, CSRF tokens, credentials and secret JSON fields are scrubbed before they are recorded
+type Recorder struct {
+	mu        sync.Mutex
+	path      string
+	transport http.RoundTripper
+	cassette  Cassette
+}
+
+// NewRecorder creates a Recorder which sends requests through the given transport, or http.DefaultTransport if nil,
+// and whose cassette is written to the given path by Save
+func NewRecorder(path string, transport http.RoundTripper) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &Recorder{path: path, transport: transport}
+}
+
+// RoundTrip sends the request and records the interaction
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		if reqBody, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	recorded := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URI:    req.URL.RequestURI(),
+			Header: scrubHeader(req.Header),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     scrubHeader(resp.Header),
+		},
+	}
+	recorded.Request.Body, recorded.Request.Encoding = scrubBody(reqBody)
+	recorded.Response.Body, recorded.Response.Encoding = scrubBody(respBody)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, recorded)
+
+	return resp, nil
+}
+
+// Cassette returns the interactions recorded so far
+func (r *Recorder) Cassette() Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return Cassette{append([]Interaction(nil), r.cassette.Interactions...)}
+}
+
+// Save writes the recorded interactions to the cassette file
+func (r *Recorder) Save() error {
+	buf, err := json.MarshalIndent(r.Cassette(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode cassette: %w", err)
+	}
+
+	if err := ioutil.WriteFile(r.path, buf, 0644); err != nil {
+		return fmt.Errorf("could not write cassette: %w", err)
+	}
+
+	return nil
+}
+
+// Replayer is an http.RoundTripper which answers requests with the interactions of a cassette, without a server.
+// Requests are matched by method and URI. Identical requests are answered with the recorded responses in order,
+// the last of which keeps being replayed once they run out
+type Replayer struct {
+	mu      sync.Mutex
+	answers map[string][]RecordedResponse
+}
+
+// NewReplayer loads the cassette at the given path
+func NewReplayer(path string) (*Replayer, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read cassette: %w", err)
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(buf, &cassette); err != nil {
+		return nil, fmt.Errorf("could not parse cassette: %w", err)
+	}
+
+	return NewReplayerFromCassette(cassette), nil
+}
+
+// NewReplayerFromCassette creates a Replayer of the given interactions
+func NewReplayerFromCassette(cassette Cassette) *Replayer {
+	r := &Replayer{answers: make(map[string][]RecordedResponse)}
+	for _, i := range cassette.Interactions {
+		key := i.Request.Method + " " + i.Request.URI
+		r.answers[key] = append(r.answers[key], i.Response)
+	}
+	return r
+}
+
+// RoundTrip answers the request with the next response recorded for it
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	key := req.Method + " " + req.URL.RequestURI()
+
+	r.mu.Lock()
+	answers := r.answers[key]
+	if len(answers) == 0 {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("no recorded interaction for %s", key)
+	}
+	answer := answers[0]
+	if len(answers) > 1 {
+		r.answers[key] = answers[1:]
+	}
+	r.mu.Unlock()
+
+	body, err := decodeBody(answer.Body, answer.Encoding)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode recorded response to %s: %w", key, err)
+	}
+
+	header := make(http.Header, len(answer.Header))
+	for k, v := range answer.Header {
+		header[k] = append([]string(nil), v...)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", answer.StatusCode, http.StatusText(answer.StatusCode)),
+		StatusCode:    answer.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,97 @@
+package privateiq
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hokiegeek/gonexus-private/iq/privateiqtest"
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cassette")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "private.json")
+
+	type results struct {
+		Policies IQPolicySet
+		Firewall []FirewallComponent
+		Waivers  []Waiver
+		Zip      []byte
+	}
+	run := func(iq publiciq.IQ) (r results) {
+		var err error
+		if r.Policies, err = ExportPolicies(iq); err != nil {
+			t.Fatal(err)
+		}
+		if r.Firewall, err = GetFirewallState(iq, "maven-central"); err != nil {
+			t.Fatal(err)
+		}
+		if r.Waivers, err = WaiversByAppIDStage(iq, "app1", publiciq.StageBuild); err != nil {
+			t.Fatal(err)
+		}
+		if r.Zip, _, err = GetSupportZip(iq); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	// Record against a server
+	server := waiversTestServer()
+	server.SetPolicies(publiciq.RootOrganization, json.RawMessage(`{"policies":[{"id":"policy1","name":"Security-High","threatLevel":9}]}`))
+	server.SetFirewallComponents("maven-central", []privateiqtest.FirewallComponent{{Hash: "1234", Quarantined: true}})
+
+	recorder := NewRecorder(path, nil)
+	recorded := run(FromPublic(server.IQ(), WithTransport(recorder)))
+	server.Close()
+
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	cassette, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(cassette, []byte(privateiqtest.Password)) {
+		t.Error("cassette contains the password")
+	}
+	for _, i := range recorder.Cassette().Interactions {
+		for _, h := range []string{"Authorization", csrfHeader} {
+			if v := i.Request.Header.Get(h); v != "" && v != redacted {
+				t.Errorf("%s header was not scrubbed: %q", h, v)
+			}
+		}
+	}
+
+	// Replay without a server
+	replayer, err := NewReplayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := publiciq.New(server.URL, "dummy_user", "dummy_pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed := run(FromPublic(pub, WithTransport(replayer)))
+
+	if !reflect.DeepEqual(recorded, replayed) {
+		t.Errorf("replayed results differ from the recorded ones\nrecorded: %v\nreplayed: %v", recorded, replayed)
+	}
+}
+
+func TestScrubBody(t *testing.T) {
+	got, _ := scrubBody([]byte(`{"url":"http://example.com","secretKey":"s3cr3t","password" : "p\"ss"}`))
+	want := `{"url":"http://example.com","secretKey":"REDACTED","password" : "REDACTED"}`
+	if got != want {
+		t.Errorf("scrubBody() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,317 @@
+package privateiq
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"time"
+
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+// Client accesses the private APIs of an IQ server.
+// It is configured once, and shares its private session, limits and hooks across all of its operations and goroutines.
+// Its IQ method returns an instance which can also be given to the publiciq functions
+type Client struct {
+	iq *privateiq
+}
+
+// New creates a Client of the IQ server at the given host, authenticated with the given credentials
+func New(host, username, password string, opts ...Option) (*Client, error) {
+	iq, err := publiciq.New(host, username, password)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(iq, opts...), nil
+}
+
+// NewClient creates a Client from a public IQ instance. Given an instance returned by FromPublic,
+// or by the IQ method of another Client, the new Client shares its private session
+func NewClient(iq publiciq.IQ, opts ...Option) *Client {
+	return &Client{FromPublic(iq, opts...).(*privateiq)}
+}
+
+// IQ returns the instance through which the client sends its requests
+func (c *Client) IQ() IQ {
+	return c.iq
+}
+
+// WithHTTPClient sets the http.Client which sends the requests
+func WithHTTPClient(client *http.Client) Option {
+	return func(iq *privateiq) {
+		iq.client = client
+	}
+}
+
+// WithTimeout sets the time limit of each request, including reading its response. Zero means no limit.
+// Streamed downloads, such as WriteSupportZip, are only bounded by their context
+func WithTimeout(timeout time.Duration) Option {
+	return func(iq *privateiq) {
+		client := *iq.client
+		client.Timeout = timeout
+		iq.client = &client
+	}
+}
+
+// WithTLSConfig sets the TLS configuration of the connections to IQ, such as to trust an internal CA.
+// It applies to an *http.Transport, or the default one; a transport set by WithTransport must be configured directly
+func WithTLSConfig(config *tls.Config) Option {
+	return func(iq *privateiq) {
+		client := *iq.client
+		switch t := client.Transport.(type) {
+		case nil:
+			transport := http.DefaultTransport.(*http.Transport).Clone()
+			transport.TLSClientConfig = config
+			client.Transport = transport
+		case *http.Transport:
+			transport := t.Clone()
+			transport.TLSClientConfig = config
+			client.Transport = transport
+		}
+		iq.client = &client
+	}
+}
+
+// DeleteOrganization deletes an organization in IQ with the given id
+func (c *Client) DeleteOrganization(ctx context.Context, organizationID string) error {
+	return DeleteOrganizationContext(ctx, c.iq, organizationID)
+}
+
+// RenameOrganization renames the organization with the given id
+func (c *Client) RenameOrganization(ctx context.Context, organizationID, name string) error {
+	return RenameOrganizationContext(ctx, c.iq, organizationID, name)
+}
+
+// MoveOrganization moves the organization with the given id, along with its descendants, under a new parent
+func (c *Client) MoveOrganization(ctx context.Context, organizationID, parentID string) error {
+	return MoveOrganizationContext(ctx, c.iq, organizationID, parentID)
+}
+
+// MoveApplication moves the application with the given public id to another organization
+func (c *Client) MoveApplication(ctx context.Context, appPublicID, organizationID string) error {
+	return MoveApplicationContext(ctx, c.iq, appPublicID, organizationID)
+}
+
+// GetOrganizationTree returns the hierarchy of the organizations, and their applications, starting at the Root Organization
+func (c *Client) GetOrganizationTree(ctx context.Context) (*OrganizationTree, error) {
+	return GetOrganizationTreeContext(ctx, c.iq)
+}
+
+// PlanOrganizationDeletion lists everything which deleting the organization, and its descendants, would remove.
+// Nothing is deleted
+func (c *Client) PlanOrganizationDeletion(ctx context.Context, organizationID string) (*DeletionPlan, error) {
+	return PlanOrganizationDeletionContext(ctx, c.iq, organizationID)
+}
+
+// ExecuteDeletionPlan deletes the applications and organizations of the plan, in order, reporting the outcome of each
+func (c *Client) ExecuteDeletionPlan(ctx context.Context, plan *DeletionPlan, continueOnError bool) ([]DeletionResult, error) {
+	return ExecuteDeletionPlanContext(ctx, c.iq, plan, continueOnError)
+}
+
+// DeleteOrganizationRecursive deletes the organization along with its descendants and their applications, bottom-up
+func (c *Client) DeleteOrganizationRecursive(ctx context.Context, organizationID string, continueOnError bool) ([]DeletionResult, error) {
+	return DeleteOrganizationRecursiveContext(ctx, c.iq, organizationID, continueOnError)
+}
+
+// CreateBackup captures the organization tree, applications, policies, component labels,
+// continuous monitoring stages, automatic applications and webhooks of IQ
+func (c *Client) CreateBackup(ctx context.Context) (*Backup, error) {
+	return CreateBackupContext(ctx, c.iq)
+}
+
+// RestoreBackup replays the backup into IQ, creating what does not exist yet and reporting what conflicted
+func (c *Client) RestoreBackup(ctx context.Context, backup *Backup) (*RestoreReport, error) {
+	return RestoreBackupContext(ctx, c.iq, backup)
+}
+
+// EvaluateComponentsWithRootOrg evaluates the list of components using Root Organization only
+func (c *Client) EvaluateComponentsWithRootOrg(ctx context.Context, components []publiciq.Component) (*publiciq.Evaluation, error) {
+	return EvaluateComponentsWithRootOrgContext(ctx, c.iq, components)
+}
+
+// EvaluateComponentsWithOrg evaluates the list of components using the policies of the organization with the given ID or name
+func (c *Client) EvaluateComponentsWithOrg(ctx context.Context, organization string, components []publiciq.Component) (*publiciq.Evaluation, error) {
+	return EvaluateComponentsWithOrgContext(ctx, c.iq, organization, components)
+}
+
+// EvaluateSBOM evaluates the components listed by a CycloneDX or SPDX document using Root Organization only
+func (c *Client) EvaluateSBOM(ctx context.Context, sbom io.Reader) (*SBOMEvaluation, error) {
+	return EvaluateSBOMContext(ctx, c.iq, sbom)
+}
+
+// EvaluateLockfile evaluates the dependencies listed by a lockfile using the policies of the organization with the given ID or name
+func (c *Client) EvaluateLockfile(ctx context.Context, organization string, format LockfileFormat, lockfile io.Reader) (*LockfileEvaluation, error) {
+	return EvaluateLockfileWithOrgContext(ctx, c.iq, organization, format, lockfile)
+}
+
+// EvaluateBinaries evaluates the files under the directory by their SHA-1 hash, using Root Organization only
+func (c *Client) EvaluateBinaries(ctx context.Context, dir string, extensions ...string) (*BinaryEvaluation, error) {
+	return EvaluateBinariesContext(ctx, c.iq, dir, extensions...)
+}
+
+// EvaluateComponentsBatch evaluates a list of components too large for a single request, by splitting it into chunks
+func (c *Client) EvaluateComponentsBatch(ctx context.Context, components []publiciq.Component, opts BatchOptions) (*BatchEvaluation, error) {
+	return EvaluateComponentsBatchContext(ctx, c.iq, components, opts)
+}
+
+// NewEvaluationPool creates a pool of temporary applications to evaluate components with
+func (c *Client) NewEvaluationPool(maxSize int) *EvaluationPool {
+	return NewEvaluationPool(c.iq, maxSize)
+}
+
+// FindTempOrganizations lists the organizations named like the temporary ones created for ad-hoc evaluations
+func (c *Client) FindTempOrganizations(ctx context.Context) ([]TempOrganization, error) {
+	return FindTempOrganizationsContext(ctx, c.iq)
+}
+
+// CollectTempOrganizations deletes the temporary organizations, and their applications, older than the given age.
+// Those of unknown age are only deleted when unknownAge is set
+func (c *Client) CollectTempOrganizations(ctx context.Context, olderThan time.Duration, unknownAge, dryRun bool) ([]TempOrganization, error) {
+	return CollectTempOrganizationsContext(ctx, c.iq, olderThan, unknownAge, dryRun)
+}
+
+// GetFirewallState returns the components in a Firewalled proxy
+func (c *Client) GetFirewallState(ctx context.Context, repoid string) ([]FirewallComponent, error) {
+	return GetFirewallStateContext(ctx, c.iq, repoid)
+}
+
+// QueryFirewallReport returns a page of the components in a Firewalled proxy which pass the filters of the query
+func (c *Client) QueryFirewallReport(ctx context.Context, repoid string, query FirewallQuery) (*FirewallPage, error) {
+	return QueryFirewallReportContext(ctx, c.iq, repoid, query)
+}
+
+// FirewallIterator creates an iterator over the components in a Firewalled proxy which pass the filters of the query
+func (c *Client) FirewallIterator(ctx context.Context, repoid string, query FirewallQuery) *FirewallIterator {
+	return NewFirewallIterator(ctx, c.iq, repoid, query)
+}
+
+// GetSupportZip generates a support zip containing everything, returning it along with its name
+func (c *Client) GetSupportZip(ctx context.Context) ([]byte, string, error) {
+	return GetSupportZipContext(ctx, c.iq)
+}
+
+// WriteSupportZip generates a support zip with the given contents and streams it to the writer, returning its name
+func (c *Client) WriteSupportZip(ctx context.Context, w io.Writer, opts SupportZipOptions) (string, error) {
+	return WriteSupportZipContext(ctx, c.iq, w, opts)
+}
+
+// CreateWebhook creates a webhook in IQ
+func (c *Client) CreateWebhook(ctx context.Context, url, secret string, eventTypes []string) error {
+	return CreateWebhookContext(ctx, c.iq, url, secret, eventTypes)
+}
+
+// EnableAutomaticApplications enables automatic applications for the given organization
+func (c *Client) EnableAutomaticApplications(ctx context.Context, orgName string) error {
+	return EnableAutomaticApplicationsContext(ctx, c.iq, orgName)
+}
+
+// DisableAutomaticApplications disables automatic applications
+func (c *Client) DisableAutomaticApplications(ctx context.Context) error {
+	return DisableAutomaticApplicationsContext(ctx, c.iq)
+}
+
+// EnableNotice sets a message in IQ
+func (c *Client) EnableNotice(ctx context.Context, text string) error {
+	return EnableNoticeContext(ctx, c.iq, text)
+}
+
+// DisableNotice disables the system notice
+func (c *Client) DisableNotice(ctx context.Context) error {
+	return DisableNoticeContext(ctx, c.iq)
+}
+
+// EnableContinuousMonitoringApplication will enable Continuous Monitoring for the given application
+func (c *Client) EnableContinuousMonitoringApplication(ctx context.Context, appPublicID, stage string) error {
+	return EnableContinuousMonitoringApplicationContext(ctx, c.iq, appPublicID, stage)
+}
+
+// EnableContinuousMonitoringOrganization will enable Continuous Monitoring for the given organization
+func (c *Client) EnableContinuousMonitoringOrganization(ctx context.Context, orgName, stage string) error {
+	return EnableContinuousMonitoringOrganizationContext(ctx, c.iq, orgName, stage)
+}
+
+// DisableContinuousMonitoringApplication will disable Continuous Monitoring for the given application
+func (c *Client) DisableContinuousMonitoringApplication(ctx context.Context, appPublicID string) error {
+	return DisableContinuousMonitoringApplicationContext(ctx, c.iq, appPublicID)
+}
+
+// DisableContinuousMonitoringOrganization will disable Continuous Monitoring for the given organization
+func (c *Client) DisableContinuousMonitoringOrganization(ctx context.Context, orgName string) error {
+	return DisableContinuousMonitoringOrganizationContext(ctx, c.iq, orgName)
+}
+
+// TriggerContinuousMonitoring will test trigger continuous monitoring
+func (c *Client) TriggerContinuousMonitoring(ctx context.Context) error {
+	return TriggerContinuousMonitoringContext(ctx, c.iq)
+}
+
+// GetAllComponentLabels returns the component labels of the Root Organization
+func (c *Client) GetAllComponentLabels(ctx context.Context) ([]IqComponentLabel, error) {
+	return GetAllComponentLabelsContext(ctx, c.iq)
+}
+
+// CreateComponentLabel creates a component label in the given organization
+func (c *Client) CreateComponentLabel(ctx context.Context, organization, label, description, color string) error {
+	return CreateComponentLabelContext(ctx, c.iq, organization, label, description, color)
+}
+
+// InstallLicense allows for an IQ license to be installed
+func (c *Client) InstallLicense(ctx context.Context, license io.Reader) error {
+	return InstallLicenseContext(ctx, c.iq, license)
+}
+
+// LicenseInfo returns the license installed in IQ
+func (c *Client) LicenseInfo(ctx context.Context) (NexusLicense, error) {
+	return LicenseInfoContext(ctx, c.iq)
+}
+
+// ReevaluateReportByID hits the re-eval button on the specified report
+func (c *Client) ReevaluateReportByID(ctx context.Context, appID, ReportID string) error {
+	return ReevaluateReportByIDContext(ctx, c.iq, appID, ReportID)
+}
+
+// ReevaluateReportByApp hits the re-eval button
+func (c *Client) ReevaluateReportByApp(ctx context.Context, appID, stage string) error {
+	return ReevaluateReportByAppContext(ctx, c.iq, appID, stage)
+}
+
+// ReevaluateAllReports hits the re-eval button on AllTheThings!
+func (c *Client) ReevaluateAllReports(ctx context.Context) error {
+	return ReevaluateAllReportsContext(ctx, c.iq)
+}
+
+// ExportPolicies returns the policies of the indicated IQ server
+func (c *Client) ExportPolicies(ctx context.Context) (IQPolicySet, error) {
+	return ExportPoliciesContext(ctx, c.iq)
+}
+
+// ImportPolicies imports the given policies
+func (c *Client) ImportPolicies(ctx context.Context, file io.Reader) error {
+	return ImportPoliciesContext(ctx, c.iq, file)
+}
+
+// ServerVersion returns the version of the IQ server. It is detected once per client
+func (c *Client) ServerVersion(ctx context.Context) (Version, error) {
+	return ServerVersionContext(ctx, c.iq)
+}
+
+// VulnerabilityInfoHTML returns an HTML representation of the Vulnerability Info panel of a given vulnerability
+func (c *Client) VulnerabilityInfoHTML(ctx context.Context, vulnerabilityID string) (string, error) {
+	return VulnerabilityInfoHTMLContext(ctx, c.iq, vulnerabilityID)
+}
+
+// WaiversByAppIDStage returns the waivers associated with an application
+func (c *Client) WaiversByAppIDStage(ctx context.Context, appID, stage string) ([]Waiver, error) {
+	return WaiversByAppIDStageContext(ctx, c.iq, appID, stage)
+}
+
+// WaiversByAppID returns the waivers associated with an application
+func (c *Client) WaiversByAppID(ctx context.Context, appID string) ([]Waiver, error) {
+	return WaiversByAppIDContext(ctx, c.iq, appID)
+}
+
+// Waivers returns the waivers for all applications in IQ
+func (c *Client) Waivers(ctx context.Context) ([]Waiver, error) {
+	return WaiversContext(ctx, c.iq)
+}
@@ -0,0 +1,85 @@
+package privateiq
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hokiegeek/gonexus-private/iq/privateiqtest"
+)
+
+func TestClientSharesSession(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+	org := server.AddOrganization("foobar", "")
+
+	client, err := New(server.URL, privateiqtest.Username, privateiqtest.Password, WithTimeout(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if _, err := client.LicenseInfo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.EnableNotice(ctx, "maintenance"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.DeleteOrganization(ctx, org.ID); err != nil {
+		t.Fatal(err)
+	}
+	// The free functions given the client's instance use its session too
+	if err := DisableNotice(client.IQ()); err != nil {
+		t.Fatal(err)
+	}
+
+	var sessions int
+	for _, r := range server.Requests() {
+		if r == "GET "+restSessionPrivate {
+			sessions++
+		}
+	}
+	if sessions != 1 {
+		t.Errorf("established %d sessions, want 1", sessions)
+	}
+}
+
+func TestClientTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, restSessionPrivate) {
+			http.SetCookie(w, &http.Cookie{Name: csrfCookie, Value: "token"})
+			return
+		}
+		fmt.Fprint(w, `{"productEdition":"Enterprise"}`)
+	}))
+	defer server.Close()
+
+	untrusted, err := New(server.URL, "user", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := untrusted.LicenseInfo(context.Background()); err == nil {
+		t.Fatal("expected the certificate of the server not to be trusted")
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	client, err := New(server.URL, "user", "pass", WithTLSConfig(&tls.Config{RootCAs: pool}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	license, err := client.LicenseInfo(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if license.ProductEdition != "Enterprise" {
+		t.Errorf("unexpected license: %v", license)
+	}
+}
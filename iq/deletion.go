@@ -0,0 +1,309 @@
+package privateiq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+// PlanItemKind is the kind of an item removed by a recursive organization delete
+type PlanItemKind string
+
+// The kinds of items removed by a recursive organization delete
+const (
+	PlanOrganization PlanItemKind = "organization"
+	PlanApplication  PlanItemKind = "application"
+	PlanWaiver       PlanItemKind = "waiver"
+	PlanLabel        PlanItemKind = "label"
+	PlanMonitoring   PlanItemKind = "monitoring"
+)
+
+// PlanItem is an item removed by a recursive organization delete.
+// Waivers, labels and monitoring settings are removed by IQ along with the application or organization which owns them
+type PlanItem struct {
+	Kind    PlanItemKind
+	ID      string
+	Name    string
+	OwnerID string
+}
+
+// deleted reports whether the item is deleted by a request of its own, rather than along with its owner
+func (i PlanItem) deleted() bool {
+	return i.Kind == PlanOrganization || i.Kind == PlanApplication
+}
+
+// DeletionPlan lists everything a recursive organization delete removes, in the order it is removed:
+// bottom-up, each application and organization preceded by what it owns
+type DeletionPlan struct {
+	OrganizationID string
+	Items          []PlanItem
+	// UnlistedWaivers holds, by application public ID, why its waivers could not be listed, such as on versions
+	// of IQ without the private API. IQ removes them all the same, so they do not keep the plan from being executed
+	UnlistedWaivers map[string]error
+}
+
+// Count returns the number of items of the given kind in the plan
+func (p *DeletionPlan) Count(kind PlanItemKind) int {
+	var n int
+	for _, i := range p.Items {
+		if i.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// DeletionResult is the outcome of deleting an item of a plan
+type DeletionResult struct {
+	Item PlanItem
+	Err  error
+	// Skipped is set when the deletion was not attempted, as something the item holds was not deleted.
+	// Err then tells which
+	Skipped bool
+}
+
+// PlanOrganizationDeletion lists everything which deleting the organization, and its descendants, would remove.
+// Nothing is deleted
+func PlanOrganizationDeletion(iq publiciq.IQ, organizationID string) (*DeletionPlan, error) {
+	return PlanOrganizationDeletionContext(context.Background(), iq, organizationID)
+}
+
+// PlanOrganizationDeletionContext lists everything which deleting the organization, and its descendants, would remove.
+// Nothing is deleted
+func PlanOrganizationDeletionContext(ctx context.Context, iq publiciq.IQ, organizationID string) (*DeletionPlan, error) {
+	piq := fromPublicContext(ctx, iq)
+
+	if organizationID == publiciq.RootOrganization {
+		return nil, errors.New("the Root Organization cannot be deleted")
+	}
+
+	tree, err := GetOrganizationTreeContext(ctx, piq)
+	if err != nil {
+		return nil, err
+	}
+	org, ok := tree.Find(organizationID)
+	if !ok {
+		return nil, fmt.Errorf("organization '%s': %w", organizationID, ErrNotFound)
+	}
+
+	// Waivers are only found through the components of the applications they apply to,
+	// so gather those owned by any application or organization being deleted
+	owners := make(map[string]bool)
+	org.Walk(func(n *OrganizationNode) error {
+		owners[n.ID] = true
+		for _, a := range n.Applications {
+			owners[a.PublicID] = true
+		}
+		return nil
+	})
+	plan := &DeletionPlan{OrganizationID: organizationID, UnlistedWaivers: make(map[string]error)}
+
+	waivers := make(map[string][]PlanItem)
+	seen := make(map[string]bool)
+	err = org.Walk(func(n *OrganizationNode) error {
+		for _, a := range n.Applications {
+			appWaivers, err := WaiversByAppIDContext(ctx, piq, a.PublicID)
+			if cerr := ctx.Err(); cerr != nil {
+				return cerr
+			}
+			if err != nil {
+				plan.UnlistedWaivers[a.PublicID] = err
+			}
+			for _, w := range appWaivers {
+				if owners[w.OwnerID] && !seen[w.ID] {
+					seen[w.ID] = true
+					waivers[w.OwnerID] = append(waivers[w.OwnerID], PlanItem{Kind: PlanWaiver, ID: w.ID, Name: w.PolicyName, OwnerID: w.OwnerID})
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var visit func(n *OrganizationNode) error
+	visit = func(n *OrganizationNode) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for _, c := range n.Children {
+			if err := visit(c); err != nil {
+				return err
+			}
+		}
+
+		for _, a := range n.Applications {
+			plan.Items = append(plan.Items, waivers[a.PublicID]...)
+			stage, err := getMonitoringStage(piq, "EnableContinuousMonitoringApplication", a.ID)
+			if err != nil {
+				return err
+			}
+			if stage != "" {
+				plan.Items = append(plan.Items, PlanItem{Kind: PlanMonitoring, ID: a.ID, Name: a.PublicID, OwnerID: a.ID})
+			}
+			plan.Items = append(plan.Items, PlanItem{Kind: PlanApplication, ID: a.ID, Name: a.PublicID, OwnerID: n.ID})
+		}
+
+		plan.Items = append(plan.Items, waivers[n.ID]...)
+		labels, err := getComponentLabels(piq, n.ID)
+		if err != nil {
+			return err
+		}
+		for _, l := range labels {
+			plan.Items = append(plan.Items, PlanItem{Kind: PlanLabel, ID: l.ID, Name: l.Label, OwnerID: n.ID})
+		}
+		stage, err := getMonitoringStage(piq, "EnableContinuousMonitoringOrganization", n.ID)
+		if err != nil {
+			return err
+		}
+		if stage != "" {
+			plan.Items = append(plan.Items, PlanItem{Kind: PlanMonitoring, ID: n.ID, Name: n.Name, OwnerID: n.ID})
+		}
+
+		parentID := ""
+		if n.Parent != nil {
+			parentID = n.Parent.ID
+		}
+		plan.Items = append(plan.Items, PlanItem{Kind: PlanOrganization, ID: n.ID, Name: n.Name, OwnerID: parentID})
+
+		return nil
+	}
+	if err := visit(org); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// getComponentLabels returns the component labels owned by the given organization
+func getComponentLabels(iq *privateiq, organizationID string) ([]IqComponentLabel, error) {
+	endpoint, err := iq.endpoint("GetAllComponentLabels", organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	body, _, err := iq.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve labels of organization '%s': %w", organizationID, err)
+	}
+
+	var labels []IqComponentLabel
+	if err = json.Unmarshal(body, &labels); err != nil {
+		return nil, fmt.Errorf("could not parse labels of organization '%s': %w", organizationID, err)
+	}
+
+	owned := labels[:0]
+	for _, l := range labels {
+		if l.OwnerID == organizationID {
+			owned = append(owned, l)
+		}
+	}
+	return owned, nil
+}
+
+// getMonitoringStage returns the stage continuously monitored for the application or organization, if any
+func getMonitoringStage(iq *privateiq, function, ownerID string) (string, error) {
+	endpoint, err := iq.endpoint(function, ownerID)
+	if err != nil {
+		return "", err
+	}
+
+	body, _, err := iq.Get(endpoint)
+	if errors.Is(err, ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not retrieve monitoring of '%s': %w", ownerID, err)
+	}
+
+	var resp policyMonitoringRequest
+	if err = json.Unmarshal(body, &resp); err != nil {
+		return "", fmt.Errorf("could not parse monitoring of '%s': %w", ownerID, err)
+	}
+
+	return resp.StageTypeID, nil
+}
+
+// ExecuteDeletionPlan deletes the applications and organizations of the plan, in order, reporting the outcome of each.
+// Unless told to continue on errors, it stops at the first one. Either way, an error is returned if any deletion failed.
+// The organizations holding anything not deleted are skipped, as IQ would refuse to delete them
+func ExecuteDeletionPlan(iq publiciq.IQ, plan *DeletionPlan, continueOnError bool) ([]DeletionResult, error) {
+	return ExecuteDeletionPlanContext(context.Background(), iq, plan, continueOnError)
+}
+
+// ExecuteDeletionPlanContext deletes the applications and organizations of the plan, in order, reporting the outcome of each.
+// Unless told to continue on errors, it stops at the first one. Either way, an error is returned if any deletion failed.
+// The organizations holding anything not deleted are skipped, as IQ would refuse to delete them
+func ExecuteDeletionPlanContext(ctx context.Context, iq publiciq.IQ, plan *DeletionPlan, continueOnError bool) ([]DeletionResult, error) {
+	piq := fromPublicContext(ctx, iq)
+
+	results := make([]DeletionResult, 0, len(plan.Items))
+	// The plan being bottom-up, the owner of an item not deleted comes after it
+	kept := make(map[string]string)
+	var failed, skipped int
+	var firstErr error
+	for _, item := range plan.Items {
+		if !item.deleted() {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return results, fmt.Errorf("deletion stopped: %w", err)
+		}
+
+		if held, ok := kept[item.ID]; ok {
+			results = append(results, DeletionResult{Item: item, Err: fmt.Errorf("%s still holds %s", item.Name, held), Skipped: true})
+			kept[item.OwnerID] = fmt.Sprintf("%s '%s'", item.Kind, item.Name)
+			skipped++
+			continue
+		}
+
+		var err error
+		switch item.Kind {
+		case PlanApplication:
+			err = deleteApplication(piq, item.ID)
+		case PlanOrganization:
+			err = DeleteOrganizationContext(ctx, piq, item.ID)
+		}
+		results = append(results, DeletionResult{Item: item, Err: err})
+
+		if err != nil {
+			kept[item.OwnerID] = fmt.Sprintf("%s '%s'", item.Kind, item.Name)
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+			if !continueOnError {
+				return results, err
+			}
+		}
+	}
+
+	if failed > 0 {
+		return results, fmt.Errorf("%d items not deleted, and %d skipped as they held them, the first because: %w", failed, skipped, firstErr)
+	}
+
+	return results, nil
+}
+
+// DeleteOrganizationRecursive deletes the organization along with its descendants and their applications, bottom-up.
+// See ExecuteDeletionPlan
+func DeleteOrganizationRecursive(iq publiciq.IQ, organizationID string, continueOnError bool) ([]DeletionResult, error) {
+	return DeleteOrganizationRecursiveContext(context.Background(), iq, organizationID, continueOnError)
+}
+
+// DeleteOrganizationRecursiveContext deletes the organization along with its descendants and their applications, bottom-up.
+// See ExecuteDeletionPlan
+func DeleteOrganizationRecursiveContext(ctx context.Context, iq publiciq.IQ, organizationID string, continueOnError bool) ([]DeletionResult, error) {
+	piq := fromPublicContext(ctx, iq)
+
+	plan, err := PlanOrganizationDeletionContext(ctx, piq, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ExecuteDeletionPlanContext(ctx, piq, plan, continueOnError)
+}
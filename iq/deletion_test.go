@@ -0,0 +1,180 @@
+package privateiq
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hokiegeek/gonexus-private/iq/privateiqtest"
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+// deletionTestServer has a business unit with a child organization, each holding an application
+func deletionTestServer(t *testing.T) (*privateiqtest.Server, privateiqtest.Organization) {
+	server := privateiqtest.NewServer()
+
+	unit := server.AddOrganization("unit", "")
+	team := server.AddOrganization("team", unit.ID)
+	server.AddOrganization("other", "")
+	server.AddApplication("unitapp", "unitapp", unit.ID)
+	server.AddApplication("teamapp", "teamapp", team.ID)
+	server.AddReport("teamapp", publiciq.StageBuild, publiciq.Component{Hash: "hash1"})
+	server.AddWaiver("teamapp", privateiqtest.Waiver{Hash: "hash1", PolicyID: "policy1", PolicyName: "Security-High"})
+	server.AddComponentLabel(team.ID, privateiqtest.ComponentLabel{Label: "Foo", Color: "orange"})
+
+	if err := EnableContinuousMonitoringOrganization(server.IQ(), "team", publiciq.StageBuild); err != nil {
+		t.Fatal(err)
+	}
+
+	return server, unit
+}
+
+func TestPlanOrganizationDeletion(t *testing.T) {
+	server, unit := deletionTestServer(t)
+	defer server.Close()
+
+	plan, err := PlanOrganizationDeletion(server.IQ(), unit.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for kind, want := range map[PlanItemKind]int{
+		PlanOrganization: 2,
+		PlanApplication:  2,
+		PlanWaiver:       1,
+		PlanLabel:        1,
+		PlanMonitoring:   1,
+	} {
+		if got := plan.Count(kind); got != want {
+			t.Errorf("plan has %d %s items, want %d: %v", got, kind, want, plan.Items)
+		}
+	}
+
+	// Bottom-up: the child organization goes before its parent, and applications before their organization
+	var order []string
+	for _, i := range plan.Items {
+		if i.deleted() {
+			order = append(order, i.Name)
+		}
+	}
+	want := []string{"teamapp", "team", "unitapp", "unit"}
+	if len(order) != len(want) {
+		t.Fatalf("deletion order %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("deletion order %v, want %v", order, want)
+		}
+	}
+
+	if len(server.Organizations()) != 4 || len(server.Applications()) != 2 {
+		t.Error("planning deleted something")
+	}
+
+	if _, err := PlanOrganizationDeletion(server.IQ(), publiciq.RootOrganization); err == nil {
+		t.Error("expected planning to delete the Root Organization to fail")
+	}
+	if _, err := PlanOrganizationDeletion(server.IQ(), "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestDeleteOrganizationRecursive(t *testing.T) {
+	server, unit := deletionTestServer(t)
+	defer server.Close()
+
+	results, err := DeleteOrganizationRecursive(server.IQ(), unit.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 {
+		t.Errorf("got %d results, want 4: %v", len(results), results)
+	}
+
+	if orgs := server.Organizations(); len(orgs) != 2 {
+		t.Errorf("organizations left: %v", orgs)
+	}
+	if apps := server.Applications(); len(apps) != 0 {
+		t.Errorf("applications left: %v", apps)
+	}
+	if labels := server.ComponentLabels(); len(labels) != 0 {
+		t.Errorf("labels left: %v", labels)
+	}
+}
+
+func TestExecuteDeletionPlanErrors(t *testing.T) {
+	server, unit := deletionTestServer(t)
+	defer server.Close()
+
+	plan, err := PlanOrganizationDeletion(server.IQ(), unit.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An application created after planning keeps its organization from being deleted
+	var team string
+	for _, i := range plan.Items {
+		if i.Kind == PlanOrganization && i.Name == "team" {
+			team = i.ID
+		}
+	}
+	server.AddApplication("lateapp", "lateapp", team)
+
+	results, err := ExecuteDeletionPlan(server.IQ(), plan, false)
+	if err == nil || len(results) != 2 || results[1].Err == nil {
+		t.Fatalf("expected stopping at the failed deletion of team, got %v: %v", err, results)
+	}
+
+	plan, err = PlanOrganizationDeletion(server.IQ(), unit.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.AddApplication("lateapp2", "lateapp2", team)
+
+	results, err = ExecuteDeletionPlan(server.IQ(), plan, true)
+	if err == nil {
+		t.Fatal("expected an error reporting the failed deletions")
+	}
+	var failed, skipped int
+	for _, r := range results {
+		switch {
+		case r.Skipped:
+			skipped++
+		case r.Err != nil:
+			failed++
+		}
+	}
+	if len(results) != 4 || failed != 1 || skipped != 1 || !results[3].Skipped || results[3].Item.Name != "unit" {
+		t.Errorf("expected team to fail and unit, which holds it, to be skipped, got %v", results)
+	}
+	if len(server.Requests()) > 0 && server.Requests()[len(server.Requests())-1] == "DELETE rest/organization/"+unit.ID {
+		t.Error("the deletion of unit was attempted although it still held team")
+	}
+	if apps := server.Applications(); len(apps) != 1 || apps[0].PublicID != "lateapp2" {
+		t.Errorf("unexpected applications left: %v", apps)
+	}
+}
+
+func TestPlanWithoutWaivers(t *testing.T) {
+	server, unit := deletionTestServer(t)
+	defer server.Close()
+
+	// Versions without the private waiver API are planned, and deleted, all the same
+	server.SetVersion("1.40.0-01")
+	plan, err := PlanOrganizationDeletion(server.IQ(), unit.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Count(PlanWaiver) != 0 || len(plan.UnlistedWaivers) != 1 || !errors.Is(plan.UnlistedWaivers["teamapp"], ErrUnsupportedVersion) {
+		t.Errorf("waivers were not reported as unlisted: %v", plan.UnlistedWaivers)
+	}
+	if plan.Count(PlanOrganization) != 2 || plan.Count(PlanApplication) != 2 {
+		t.Errorf("incomplete plan: %v", plan.Items)
+	}
+
+	if _, err := ExecuteDeletionPlan(server.IQ(), plan, false); err != nil {
+		t.Fatal(err)
+	}
+	if orgs := server.Organizations(); len(orgs) != 2 {
+		t.Errorf("organizations left: %v", orgs)
+	}
+}
@@ -2,12 +2,13 @@ package privateiq
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/rand"
-	"mime"
-	"net/http"
-	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
@@ -16,8 +17,8 @@ import (
 const (
 	restOrganizationPrivate = "rest/organization/%s"
 	restFirewallPrivate     = "rest/repositories/%s/report/details"
+	restFirewallPaged       = "rest/repositories/%s/report/details?%s"
 	restWebhooks            = "rest/config/webhook"
-	restSupportZip          = "rest/support?noLimit=true"
 	restAutoApps            = "rest/config/automaticApplications"
 	restSystemNotice        = "rest/config/systemNotice"
 	restMonitoringOrg       = "rest/policyMonitoring/organization/%s"
@@ -31,10 +32,10 @@ type FirewallComponent struct {
 	ComponentDisplayText string                       `json:"componentDisplayText"`
 	Pathname             string                       `json:"pathname"`
 	Hash                 string                       `json:"hash"`
-	MatchState           string                       `json:"matchState"`
+	MatchState           MatchState                   `json:"matchState"`
 	Quarantined          bool                         `json:"quarantined"`
 	Waived               bool                         `json:"waived"`
-	ThreatLevel          int                          `json:"threatLevel"`
+	ThreatLevel          ThreatLevel                  `json:"threatLevel"`
 	HighestThreatLevel   bool                         `json:"highestThreatLevel"`
 	PolicyName           string                       `json:"policyName"`
 }
@@ -78,115 +79,273 @@ type policyMonitoringResponse struct {
 	StageTypeID string `json:"stageTypeId"`
 }*/
 
-func createTempApplication(iq publiciq.IQ) (orgID string, appName string, appID string, err error) {
-	rand.Seed(time.Now().UnixNano())
-	name := strconv.Itoa(rand.Int())
+// DefaultTempPrefix starts the names of the temporary organizations and applications
+// created for ad-hoc evaluations, unless WithTempPrefix sets another one
+const DefaultTempPrefix = "privateiq-tmp"
 
-	orgID, err = publiciq.CreateOrganization(iq, name)
-	if err != nil {
-		return
+// tempTimeFormat is the timestamp following the prefix in the names of temporary resources
+const tempTimeFormat = "20060102-150405"
+
+// WithTempPrefix sets the prefix of the names of the temporary organizations and applications
+// created for ad-hoc evaluations, which are named after it and the time they were created at
+func WithTempPrefix(prefix string) Option {
+	return func(iq *privateiq) {
+		iq.tempPrefix = prefix
+	}
+}
+
+// tempName returns an identifiable name for a temporary resource, such as privateiq-tmp-20200101-000000-1a2b
+func (iq privateiq) tempName() string {
+	prefix := iq.tempPrefix
+	if prefix == "" {
+		prefix = DefaultTempPrefix
 	}
 
-	appName = fmt.Sprintf("%s_app", name)
+	tempRand.Lock()
+	suffix := tempRand.Intn(0x10000)
+	tempRand.Unlock()
 
-	appID, err = publiciq.CreateApplication(iq, appName, appName, orgID)
-	if err != nil {
-		return
+	return fmt.Sprintf("%s-%s-%04x", prefix, time.Now().UTC().Format(tempTimeFormat), suffix)
+}
+
+// tempRand tells apart the temporary resources created within the same second
+var tempRand = struct {
+	sync.Mutex
+	*rand.Rand
+}{Rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+// CleanupError is returned when the temporary resources created by a function could not all be deleted.
+// Whatever the function produced is returned along with it
+type CleanupError struct {
+	// Err is the error of the function itself, if it failed
+	Err error
+	// Leaked describes each resource which was not deleted
+	Leaked []string
+	// Errs are the errors which prevented deleting them
+	Errs []error
+}
+
+func (e *CleanupError) Error() string {
+	var b strings.Builder
+	if e.Err != nil {
+		fmt.Fprintf(&b, "%v; ", e.Err)
 	}
+	fmt.Fprintf(&b, "could not delete %s", strings.Join(e.Leaked, ", "))
+	for _, err := range e.Errs {
+		fmt.Fprintf(&b, ": %v", err)
+	}
+	return b.String()
+}
 
-	return
+// Unwrap returns the error of the function itself
+func (e *CleanupError) Unwrap() error {
+	return e.Err
+}
+
+// tempApplication tracks the temporary resources created to evaluate components
+type tempApplication struct {
+	iq    *privateiq
+	name  string
+	orgID string
+	appID string
 }
 
-func deleteTempApplication(iq publiciq.IQ, applicationPublicID string) error {
-	appInfo, err := publiciq.GetApplicationByPublicID(iq, applicationPublicID)
+// createTempApplication creates an application in a new organization under the given parent,
+// which inherits the policies of the parent. Whatever was created is tracked, even when an error is returned,
+// and must be deleted with cleanup
+func createTempApplication(ctx context.Context, iq publiciq.IQ, parentID string) (*tempApplication, error) {
+	piq := fromPublicContext(ctx, iq)
+	tmp := &tempApplication{iq: piq, name: piq.tempName()}
+
+	orgID, err := createOrganization(piq, tmp.name, parentID)
 	if err != nil {
-		return err
+		return tmp, err
 	}
+	tmp.orgID = orgID
 
-	if err := publiciq.DeleteApplication(iq, appInfo.ID); err != nil {
-		return err
+	appID, err := createApplication(piq, tmp.name, tmp.name, orgID)
+	if err != nil {
+		return tmp, err
 	}
+	tmp.appID = appID
+
+	return tmp, nil
+}
 
-	DeleteOrganization(iq, appInfo.OrganizationID) // OJO: Gonna go ahead and ignore this error for now
+// cleanup deletes every resource created by createTempApplication, even once the context is done,
+// unless something else already did. The error of the function which used them is returned, wrapped in a *CleanupError if any could not be deleted
+func (t *tempApplication) cleanup(err error) error {
+	piq := t.iq.withContext(detached{t.iq.context()})
+	cerr := &CleanupError{Err: err}
+
+	if t.appID != "" {
+		if derr := deleteApplication(piq, t.appID); derr != nil && !errors.Is(derr, ErrNotFound) {
+			cerr.Leaked = append(cerr.Leaked, fmt.Sprintf("application '%s' (%s)", t.name, t.appID))
+			cerr.Errs = append(cerr.Errs, derr)
+		}
+	}
 
-	return nil
+	// The organization cannot be deleted while it holds the application
+	if t.orgID != "" && len(cerr.Leaked) == 0 {
+		if derr := DeleteOrganizationContext(piq.ctx, piq, t.orgID); derr != nil && !errors.Is(derr, ErrNotFound) {
+			cerr.Errs = append(cerr.Errs, derr)
+		}
+	}
+	if t.orgID != "" && len(cerr.Errs) > 0 {
+		cerr.Leaked = append(cerr.Leaked, fmt.Sprintf("organization '%s' (%s)", t.name, t.orgID))
+	}
+
+	if len(cerr.Leaked) > 0 {
+		return cerr
+	}
+	return err
 }
 
+// detached keeps the values of a context, such as those of hooks, without its cancellation
+type detached struct {
+	context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detached) Done() <-chan struct{}       { return nil }
+func (detached) Err() error                  { return nil }
+
 // DeleteOrganization deletes an organization in IQ with the given id
 func DeleteOrganization(iq publiciq.IQ, organizationID string) error {
-	endpoint := fmt.Sprintf(restOrganizationPrivate, organizationID)
+	return DeleteOrganizationContext(context.Background(), iq, organizationID)
+}
 
-	resp, err := FromPublic(iq).Del(endpoint)
-	if err != nil && resp.StatusCode != http.StatusNoContent {
+// DeleteOrganizationContext deletes an organization in IQ with the given id
+func DeleteOrganizationContext(ctx context.Context, iq publiciq.IQ, organizationID string) error {
+	piq := fromPublicContext(ctx, iq)
+
+	endpoint, err := piq.endpoint("DeleteOrganization", organizationID)
+	if err != nil {
 		return err
 	}
 
+	if _, err := piq.Del(endpoint); err != nil {
+		return fmt.Errorf("organization '%s' not deleted: %w", organizationID, err)
+	}
+
 	return nil
 }
 
 // EvaluateComponentsWithRootOrg evaluates the list of components using Root Organization only
 func EvaluateComponentsWithRootOrg(iq publiciq.IQ, components []publiciq.Component) (eval *publiciq.Evaluation, err error) {
-	// Create temp application
-	_, appName, appID, err := createTempApplication(iq)
+	return EvaluateComponentsWithRootOrgContext(context.Background(), iq, components)
+}
+
+// EvaluateComponentsWithRootOrgContext evaluates the list of components using Root Organization only
+func EvaluateComponentsWithRootOrgContext(ctx context.Context, iq publiciq.IQ, components []publiciq.Component) (eval *publiciq.Evaluation, err error) {
+	return EvaluateComponentsWithOrgContext(ctx, iq, publiciq.RootOrganization, components)
+}
+
+// EvaluateComponentsWithOrg evaluates the list of components using the policies of the organization with the given ID or name.
+// The temporary application it evaluates them with, and its organization, are deleted afterwards,
+// and a *CleanupError is returned if they could not be
+func EvaluateComponentsWithOrg(iq publiciq.IQ, organization string, components []publiciq.Component) (*publiciq.Evaluation, error) {
+	return EvaluateComponentsWithOrgContext(context.Background(), iq, organization, components)
+}
+
+// EvaluateComponentsWithOrgContext evaluates the list of components using the policies of the organization with the given ID or name.
+// The temporary application it evaluates them with, and its organization, are deleted afterwards,
+// and a *CleanupError is returned if they could not be
+func EvaluateComponentsWithOrgContext(ctx context.Context, iq publiciq.IQ, organization string, components []publiciq.Component) (eval *publiciq.Evaluation, err error) {
+	piq := fromPublicContext(ctx, iq)
+
+	parentID, err := getOrganizationID(piq, organization)
 	if err != nil {
-		return
+		return nil, err
 	}
-	defer deleteTempApplication(iq, appName)
 
-	// Evaluate components
-	eval, err = publiciq.EvaluateComponents(iq, components, appID)
+	tmp, err := createTempApplication(ctx, piq, parentID)
+	defer func() {
+		err = tmp.cleanup(err)
+	}()
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	return
+	return evaluateComponents(ctx, piq, components, tmp.appID)
 }
 
 // GetFirewallState returns the components in a Firewalled proxy
 func GetFirewallState(iq publiciq.IQ, repoid string) (c []FirewallComponent, err error) {
-	endpoint := fmt.Sprintf(restFirewallPrivate, repoid)
+	return GetFirewallStateContext(context.Background(), iq, repoid)
+}
+
+// GetFirewallStateContext returns the components in a Firewalled proxy
+func GetFirewallStateContext(ctx context.Context, iq publiciq.IQ, repoid string) (c []FirewallComponent, err error) {
+	piq := fromPublicContext(ctx, iq)
+
+	endpoint, err := piq.endpoint("GetFirewallState", repoid)
+	if err != nil {
+		return nil, err
+	}
+
+	body, _, err := piq.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve firewall state of repository '%s': %w", repoid, err)
+	}
 
-	body, _, err := FromPublic(iq).Get(endpoint)
 	if err = json.Unmarshal(body, &c); err != nil {
-		return
+		return nil, fmt.Errorf("could not parse firewall state of repository '%s': %w", repoid, err)
 	}
 
 	return
 }
 
-// GetSupportZip generates a support zip with the given options
+// GetSupportZip generates a support zip containing everything, returning it along with its name
 func GetSupportZip(iq publiciq.IQ) ([]byte, string, error) {
-	body, resp, err := FromPublic(iq).Get(restSupportZip)
-	if err != nil {
-		return nil, "", fmt.Errorf("error retrieving support zip: %v", err)
-	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, "", fmt.Errorf("error retrieving support zip: %s", resp.Status)
-	}
+	return GetSupportZipContext(context.Background(), iq)
+}
 
-	_, params, err := mime.ParseMediaType(resp.Header["Content-Disposition"][0])
+// GetSupportZipContext generates a support zip containing everything, returning it along with its name
+func GetSupportZipContext(ctx context.Context, iq publiciq.IQ) ([]byte, string, error) {
+	var buf bytes.Buffer
+	name, err := WriteSupportZipContext(ctx, iq, &buf, DefaultSupportZipOptions)
 	if err != nil {
-		return nil, "", fmt.Errorf("error determining name of support zip: %v", err)
+		return nil, "", err
 	}
 
-	return body, params["filename"], nil
+	return buf.Bytes(), name, nil
 }
 
 // CreateWebhook creates a webhook in IQ
 func CreateWebhook(iq publiciq.IQ, url, secret string, eventTypes []string) error {
+	return CreateWebhookContext(context.Background(), iq, url, secret, eventTypes)
+}
+
+// CreateWebhookContext creates a webhook in IQ
+func CreateWebhookContext(ctx context.Context, iq publiciq.IQ, url, secret string, eventTypes []string) error {
 	request := Webhook{URL: url, SecretKey: secret, EventTypes: eventTypes}
 
 	str, err := json.Marshal(request)
 	if err != nil {
 		return err
 	}
-	_, _, err = FromPublic(iq).Post(restWebhooks, bytes.NewBuffer(str))
+
+	piq := fromPublicContext(ctx, iq)
+	endpoint, err := piq.endpoint("CreateWebhook")
+	if err != nil {
+		return err
+	}
+
+	_, _, err = piq.Post(endpoint, bytes.NewBuffer(str))
 	return err
 }
 
 // EnableAutomaticApplications enables automatic applications for the given organization
 func EnableAutomaticApplications(iq publiciq.IQ, orgName string) error {
-	org, err := publiciq.GetOrganizationByName(iq, orgName)
+	return EnableAutomaticApplicationsContext(context.Background(), iq, orgName)
+}
+
+// EnableAutomaticApplicationsContext enables automatic applications for the given organization
+func EnableAutomaticApplicationsContext(ctx context.Context, iq publiciq.IQ, orgName string) error {
+	piq := fromPublicContext(ctx, iq)
+
+	org, err := getOrganizationByName(piq, orgName)
 	if err != nil {
 		return err
 	}
@@ -196,44 +355,91 @@ func EnableAutomaticApplications(iq publiciq.IQ, orgName string) error {
 		return err
 	}
 
-	_, _, err = FromPublic(iq).Put(restAutoApps, bytes.NewBuffer(str))
+	endpoint, err := piq.endpoint("EnableAutomaticApplications")
+	if err != nil {
+		return err
+	}
+
+	_, _, err = piq.Put(endpoint, bytes.NewBuffer(str))
 	return err
 }
 
 // DisableAutomaticApplications enables automatic applications for the given organization
 func DisableAutomaticApplications(iq publiciq.IQ) error {
+	return DisableAutomaticApplicationsContext(context.Background(), iq)
+}
+
+// DisableAutomaticApplicationsContext enables automatic applications for the given organization
+func DisableAutomaticApplicationsContext(ctx context.Context, iq publiciq.IQ) error {
 	str, err := json.Marshal(enableAutoAppsRequest{Enabled: false})
 	if err != nil {
 		return err
 	}
 
-	_, _, err = FromPublic(iq).Put(restAutoApps, bytes.NewBuffer(str))
+	piq := fromPublicContext(ctx, iq)
+	endpoint, err := piq.endpoint("DisableAutomaticApplications")
+	if err != nil {
+		return err
+	}
+
+	_, _, err = piq.Put(endpoint, bytes.NewBuffer(str))
 	return err
 }
 
 // EnableNotice sets a message in IQ
 func EnableNotice(iq publiciq.IQ, text string) error {
+	return EnableNoticeContext(context.Background(), iq, text)
+}
+
+// EnableNoticeContext sets a message in IQ
+func EnableNoticeContext(ctx context.Context, iq publiciq.IQ, text string) error {
 	str, err := json.Marshal(systemNotice{ID: "system-notice", Enabled: true, Message: text})
 	if err != nil {
 		return err
 	}
-	_, _, err = FromPublic(iq).Put(restSystemNotice, bytes.NewBuffer(str))
+
+	piq := fromPublicContext(ctx, iq)
+	endpoint, err := piq.endpoint("EnableNotice")
+	if err != nil {
+		return err
+	}
+
+	_, _, err = piq.Put(endpoint, bytes.NewBuffer(str))
 	return err
 }
 
 // DisableNotice disables the system notice
 func DisableNotice(iq publiciq.IQ) error {
+	return DisableNoticeContext(context.Background(), iq)
+}
+
+// DisableNoticeContext disables the system notice
+func DisableNoticeContext(ctx context.Context, iq publiciq.IQ) error {
 	str, err := json.Marshal(systemNotice{ID: "system-notice", Enabled: false})
 	if err != nil {
 		return err
 	}
-	_, _, err = FromPublic(iq).Put(restSystemNotice, bytes.NewBuffer(str))
+
+	piq := fromPublicContext(ctx, iq)
+	endpoint, err := piq.endpoint("DisableNotice")
+	if err != nil {
+		return err
+	}
+
+	_, _, err = piq.Put(endpoint, bytes.NewBuffer(str))
 	return err
 }
 
 // EnableContinuousMonitoringApplication will enable Continuous Monitoring for the given application
 func EnableContinuousMonitoringApplication(iq publiciq.IQ, appPublicID, stage string) error {
-	app, err := publiciq.GetApplicationByPublicID(iq, appPublicID)
+	return EnableContinuousMonitoringApplicationContext(context.Background(), iq, appPublicID, stage)
+}
+
+// EnableContinuousMonitoringApplicationContext will enable Continuous Monitoring for the given application
+func EnableContinuousMonitoringApplicationContext(ctx context.Context, iq publiciq.IQ, appPublicID, stage string) error {
+	piq := fromPublicContext(ctx, iq)
+
+	app, err := getApplicationByPublicID(piq, appPublicID)
 	if err != nil {
 		return err
 	}
@@ -243,14 +449,25 @@ func EnableContinuousMonitoringApplication(iq publiciq.IQ, appPublicID, stage st
 		return err
 	}
 
-	endpoint := fmt.Sprintf(restMonitoringApp, app.ID)
-	_, _, err = FromPublic(iq).Put(endpoint, bytes.NewBuffer(buf))
-	return nil
+	endpoint, err := piq.endpoint("EnableContinuousMonitoringApplication", app.ID)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = piq.Put(endpoint, bytes.NewBuffer(buf))
+	return err
 }
 
 // EnableContinuousMonitoringOrganization will enable Continuous Monitoring for the given organization
 func EnableContinuousMonitoringOrganization(iq publiciq.IQ, orgName, stage string) error {
-	org, err := publiciq.GetOrganizationByName(iq, orgName)
+	return EnableContinuousMonitoringOrganizationContext(context.Background(), iq, orgName, stage)
+}
+
+// EnableContinuousMonitoringOrganizationContext will enable Continuous Monitoring for the given organization
+func EnableContinuousMonitoringOrganizationContext(ctx context.Context, iq publiciq.IQ, orgName, stage string) error {
+	piq := fromPublicContext(ctx, iq)
+
+	org, err := getOrganizationByName(piq, orgName)
 	if err != nil {
 		return err
 	}
@@ -260,38 +477,76 @@ func EnableContinuousMonitoringOrganization(iq publiciq.IQ, orgName, stage strin
 		return err
 	}
 
-	endpoint := fmt.Sprintf(restMonitoringOrg, org.ID)
-	_, _, err = FromPublic(iq).Put(endpoint, bytes.NewBuffer(buf))
+	endpoint, err := piq.endpoint("EnableContinuousMonitoringOrganization", org.ID)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = piq.Put(endpoint, bytes.NewBuffer(buf))
 	return err
 }
 
 // DisableContinuousMonitoringApplication will enable Continuous Monitoring for the given application
 func DisableContinuousMonitoringApplication(iq publiciq.IQ, appPublicID string) error {
-	app, err := publiciq.GetApplicationByPublicID(iq, appPublicID)
+	return DisableContinuousMonitoringApplicationContext(context.Background(), iq, appPublicID)
+}
+
+// DisableContinuousMonitoringApplicationContext will enable Continuous Monitoring for the given application
+func DisableContinuousMonitoringApplicationContext(ctx context.Context, iq publiciq.IQ, appPublicID string) error {
+	piq := fromPublicContext(ctx, iq)
+
+	app, err := getApplicationByPublicID(piq, appPublicID)
 	if err != nil {
 		return err
 	}
 
-	endpoint := fmt.Sprintf(restMonitoringApp, app.ID)
-	_, err = FromPublic(iq).Del(endpoint)
+	endpoint, err := piq.endpoint("DisableContinuousMonitoringApplication", app.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = piq.Del(endpoint)
 	return err
 }
 
 // DisableContinuousMonitoringOrganization will enable Continuous Monitoring for the given organization
 func DisableContinuousMonitoringOrganization(iq publiciq.IQ, orgName string) error {
-	org, err := publiciq.GetOrganizationByName(iq, orgName)
+	return DisableContinuousMonitoringOrganizationContext(context.Background(), iq, orgName)
+}
+
+// DisableContinuousMonitoringOrganizationContext will enable Continuous Monitoring for the given organization
+func DisableContinuousMonitoringOrganizationContext(ctx context.Context, iq publiciq.IQ, orgName string) error {
+	piq := fromPublicContext(ctx, iq)
+
+	org, err := getOrganizationByName(piq, orgName)
+	if err != nil {
+		return err
+	}
+
+	endpoint, err := piq.endpoint("DisableContinuousMonitoringOrganization", org.ID)
 	if err != nil {
 		return err
 	}
 
-	endpoint := fmt.Sprintf(restMonitoringOrg, org.ID)
-	_, err = FromPublic(iq).Del(endpoint)
+	_, err = piq.Del(endpoint)
 	return err
 }
 
 // TriggerContinuousMonitoring will test trigger continuous monitoring
 func TriggerContinuousMonitoring(iq publiciq.IQ) error {
-	_, _, err := FromPublic(iq).Post(restMonitoringTrigger, nil)
+	return TriggerContinuousMonitoringContext(context.Background(), iq)
+}
+
+// TriggerContinuousMonitoringContext will test trigger continuous monitoring
+func TriggerContinuousMonitoringContext(ctx context.Context, iq publiciq.IQ) error {
+	piq := fromPublicContext(ctx, iq)
+
+	endpoint, err := piq.endpoint("TriggerContinuousMonitoring")
+	if err != nil {
+		return err
+	}
+
+	_, _, err = piq.Post(endpoint, nil)
 	return err
 }
 
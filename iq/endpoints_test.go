@@ -0,0 +1,405 @@
+package privateiq
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hokiegeek/gonexus-private/iq/privateiqtest"
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+func TestDeleteOrganization(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	org := server.AddOrganization("dummyOrg", "")
+
+	if err := DeleteOrganization(server.IQ(), org.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, o := range server.Organizations() {
+		if o.ID == org.ID {
+			t.Error("organization was not deleted")
+		}
+	}
+
+	if err := DeleteOrganization(server.IQ(), org.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleting a missing organization returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestCreateWebhook(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	events := []string{WebhookEventAppEval, WebhookEventViolationAlert}
+	if err := CreateWebhook(server.IQ(), "http://example.com/hook", "secret", events); err != nil {
+		t.Fatal(err)
+	}
+
+	hooks := server.Webhooks()
+	if len(hooks) != 1 || hooks[0].URL != "http://example.com/hook" || !reflect.DeepEqual(hooks[0].EventTypes, events) {
+		t.Errorf("unexpected webhooks: %v", hooks)
+	}
+}
+
+func TestAutomaticApplications(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	org := server.AddOrganization("dummyOrg", "")
+
+	if err := EnableAutomaticApplications(server.IQ(), org.Name); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.AutomaticApplications(); !got.Enabled || got.ParentOrganizationID != org.ID {
+		t.Errorf("automatic applications not enabled: %v", got)
+	}
+
+	if err := DisableAutomaticApplications(server.IQ()); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.AutomaticApplications(); got.Enabled {
+		t.Errorf("automatic applications not disabled: %v", got)
+	}
+
+	if err := EnableAutomaticApplications(server.IQ(), "missingOrg"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("enabling for a missing organization returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestNotice(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	iq := FromPublic(server.IQ())
+
+	if err := EnableNotice(iq, "maintenance tonight"); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.SystemNotice(); !got.Enabled || got.Message != "maintenance tonight" {
+		t.Errorf("notice not enabled: %v", got)
+	}
+
+	// The session is transparently established again
+	server.ExpireSessions()
+
+	if err := DisableNotice(iq); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.SystemNotice(); got.Enabled {
+		t.Errorf("notice not disabled: %v", got)
+	}
+}
+
+func TestContinuousMonitoring(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	org := server.AddOrganization("dummyOrg", "")
+	app := server.AddApplication("dummyApp", "dummyApp", org.ID)
+	iq := server.IQ()
+
+	if err := EnableContinuousMonitoringOrganization(iq, org.Name, publiciq.StageBuild); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnableContinuousMonitoringApplication(iq, app.PublicID, publiciq.StageRelease); err != nil {
+		t.Fatal(err)
+	}
+	if stage, _ := server.Monitoring(org.ID); stage != publiciq.StageBuild {
+		t.Errorf("organization monitors %q, want %q", stage, publiciq.StageBuild)
+	}
+	if stage, _ := server.Monitoring(app.ID); stage != publiciq.StageRelease {
+		t.Errorf("application monitors %q, want %q", stage, publiciq.StageRelease)
+	}
+
+	if err := TriggerContinuousMonitoring(iq); err != nil {
+		t.Fatal(err)
+	}
+	if server.MonitoringRuns() != 1 {
+		t.Errorf("monitoring triggered %d times, want 1", server.MonitoringRuns())
+	}
+
+	if err := DisableContinuousMonitoringOrganization(iq, org.Name); err != nil {
+		t.Fatal(err)
+	}
+	if err := DisableContinuousMonitoringApplication(iq, app.PublicID); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := server.Monitoring(org.ID); ok {
+		t.Error("organization is still monitored")
+	}
+	if _, ok := server.Monitoring(app.ID); ok {
+		t.Error("application is still monitored")
+	}
+}
+
+func TestEvaluateComponentsWithRootOrg(t *testing.T) {
+	evaluationPollInterval = time.Millisecond
+
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	known := publiciq.Component{Hash: "1234", PackageURL: "pkg:maven/dummy/dummy@1.0.0?type=jar"}
+	server.AddKnownComponent(privateiqtest.KnownComponent{Component: known})
+
+	eval, err := EvaluateComponentsWithRootOrg(server.IQ(), []publiciq.Component{{Hash: "1234"}, {Hash: "5678"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(eval.Results) != 2 || eval.Results[0].MatchState != "exact" || eval.Results[1].MatchState != "unknown" {
+		t.Errorf("unexpected evaluation results: %v", eval.Results)
+	}
+
+	if orgs := server.Organizations(); len(orgs) != 1 {
+		t.Errorf("temporary organization was not deleted: %v", orgs)
+	}
+	if apps := server.Applications(); len(apps) != 0 {
+		t.Errorf("temporary application was not deleted: %v", apps)
+	}
+}
+
+func TestEvaluateComponentsWithOrg(t *testing.T) {
+	evaluationPollInterval = time.Millisecond
+
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	payments := server.AddOrganization("Payments", "")
+	server.AddKnownComponent(privateiqtest.KnownComponent{Component: publiciq.Component{Hash: "1234"}})
+
+	// Record the parent of the temporary organization while the components are evaluated
+	var parents []string
+	hooks := HookFuncs{Before: func(ctx context.Context, info RequestInfo) context.Context {
+		if info.Method == http.MethodPost && strings.HasPrefix(info.Path, "api/v2/evaluation/") {
+			for _, o := range server.Organizations() {
+				if o.ID != payments.ID && o.ID != publiciq.RootOrganization {
+					parents = append(parents, o.ParentOrganizationID)
+				}
+			}
+		}
+		return ctx
+	}}
+	iq := FromPublic(server.IQ(), WithHooks(hooks))
+
+	for _, org := range []string{payments.Name, payments.ID} {
+		parents = nil
+		eval, err := EvaluateComponentsWithOrg(iq, org, []publiciq.Component{{Hash: "1234"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(eval.Results) != 1 || eval.Results[0].MatchState != "exact" {
+			t.Errorf("unexpected evaluation results: %v", eval.Results)
+		}
+		if len(parents) != 1 || parents[0] != payments.ID {
+			t.Errorf("evaluating with %q used organizations under %v, want %q", org, parents, payments.ID)
+		}
+		if orgs := server.Organizations(); len(orgs) != 2 {
+			t.Errorf("temporary organization was not deleted: %v", orgs)
+		}
+		if apps := server.Applications(); len(apps) != 0 {
+			t.Errorf("temporary application was not deleted: %v", apps)
+		}
+	}
+
+	if _, err := EvaluateComponentsWithOrg(iq, "missing", nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("evaluating with a missing organization returned %v, want ErrNotFound", err)
+	}
+}
+
+// failing is a transport which answers the requests matching a prefix, such as "DELETE rest/organization/", with an error
+type failing struct {
+	prefix string
+}
+
+func (f failing) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.HasPrefix(req.Method+" "+strings.TrimPrefix(req.URL.Path, "/"), f.prefix) {
+		return &http.Response{
+			StatusCode: http.StatusInternalServerError,
+			Header:     make(http.Header),
+			Body:       ioutil.NopCloser(strings.NewReader("failed")),
+			Request:    req,
+		}, nil
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestTempApplicationCleanup(t *testing.T) {
+	evaluationPollInterval = time.Millisecond
+
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	components := []publiciq.Component{{Hash: "1234"}}
+	onEvaluation := func(f func(ctx context.Context) context.Context) Hook {
+		return HookFuncs{Before: func(ctx context.Context, info RequestInfo) context.Context {
+			if info.Method == http.MethodPost && strings.HasPrefix(info.Path, "api/v2/evaluation/") {
+				return f(ctx)
+			}
+			return ctx
+		}}
+	}
+	leaked := func() bool {
+		return len(server.Organizations()) != 1 || len(server.Applications()) != 0
+	}
+
+	t.Run("prefix", func(t *testing.T) {
+		var names []string
+		iq := FromPublic(server.IQ(), WithTempPrefix("ci"), WithHooks(onEvaluation(func(ctx context.Context) context.Context {
+			for _, a := range server.Applications() {
+				names = append(names, a.PublicID)
+			}
+			return ctx
+		})))
+
+		if _, err := EvaluateComponentsWithRootOrg(iq, components); err != nil {
+			t.Fatal(err)
+		}
+		if len(names) != 1 || !regexp.MustCompile(`^ci-\d{8}-\d{6}-[0-9a-f]{4}$`).MatchString(names[0]) {
+			t.Errorf("temporary application named %v", names)
+		}
+	})
+
+	t.Run("application not created", func(t *testing.T) {
+		iq := FromPublic(server.IQ(), WithTransport(failing{"POST api/v2/applications"}))
+
+		if _, err := EvaluateComponentsWithRootOrg(iq, components); err == nil {
+			t.Error("evaluation did not fail")
+		}
+		if leaked() {
+			t.Errorf("temporary organization was not deleted: %v", server.Organizations())
+		}
+	})
+
+	t.Run("organization not deleted", func(t *testing.T) {
+		iq := FromPublic(server.IQ(), WithTransport(failing{"DELETE rest/organization/"}))
+
+		eval, err := EvaluateComponentsWithRootOrg(iq, components)
+		var cerr *CleanupError
+		if !errors.As(err, &cerr) || len(cerr.Leaked) != 1 || cerr.Err != nil {
+			t.Fatalf("got error %v, want a *CleanupError leaking the organization", err)
+		}
+		if eval == nil {
+			t.Error("the evaluation was not returned along with the cleanup error")
+		}
+
+		orgs := server.Organizations()
+		if len(orgs) != 2 || !strings.Contains(cerr.Leaked[0], orgs[1].ID) {
+			t.Errorf("leaked %v, want the organization of %v", cerr.Leaked, orgs)
+		}
+		if err := DeleteOrganization(server.IQ(), orgs[1].ID); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		iq := FromPublic(server.IQ(), WithHooks(onEvaluation(func(ctx context.Context) context.Context {
+			cancel()
+			return ctx
+		})))
+
+		if _, err := EvaluateComponentsWithRootOrgContext(ctx, iq, components); !errors.Is(err, context.Canceled) {
+			t.Errorf("got error %v, want context.Canceled", err)
+		}
+		if leaked() {
+			t.Errorf("temporary resources were not deleted: %v, %v", server.Organizations(), server.Applications())
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		iq := FromPublic(server.IQ(), WithHooks(onEvaluation(func(ctx context.Context) context.Context {
+			panic("hook failed")
+		})))
+
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("the panic was not propagated")
+				}
+			}()
+			EvaluateComponentsWithRootOrg(iq, components)
+		}()
+		if leaked() {
+			t.Errorf("temporary resources were not deleted: %v, %v", server.Organizations(), server.Applications())
+		}
+	})
+}
+
+func TestGetFirewallState(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	server.SetFirewallComponents("maven-central", []privateiqtest.FirewallComponent{
+		{Hash: "1234", MatchState: "exact", Quarantined: true, ThreatLevel: 9, PolicyName: "Security-High"},
+	})
+
+	components, err := GetFirewallState(server.IQ(), "maven-central")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(components) != 1 || components[0].Hash != "1234" || !components[0].Quarantined {
+		t.Errorf("unexpected firewall state: %v", components)
+	}
+
+	if _, err := GetFirewallState(server.IQ(), "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing repository returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestGetSupportZip(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	buf, name, err := GetSupportZip(server.IQ())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "support-20200101-000000.zip" {
+		t.Errorf("got support zip named %q", name)
+	}
+	if _, err := zip.NewReader(bytes.NewReader(buf), int64(len(buf))); err != nil {
+		t.Errorf("support zip is not a valid archive: %v", err)
+	}
+}
+
+func TestComponentLabels(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	if err := CreateComponentLabel(server.IQ(), publiciq.RootOrganization, "Foo", "bar", "orange"); err != nil {
+		t.Fatal(err)
+	}
+
+	labels, err := GetAllComponentLabels(server.IQ())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(labels) != 1 || labels[0].Label != "Foo" || labels[0].LabelLowercase != "foo" {
+		t.Errorf("unexpected labels: %v", labels)
+	}
+}
+
+func TestVulnerabilityInfoHTML(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	info, err := VulnerabilityInfoHTML(server.IQ(), "CVE-2019-1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info != "<h2>CVE-2019-1234</h2>" {
+		t.Errorf("unexpected vulnerability info: %q", info)
+	}
+}
@@ -0,0 +1,79 @@
+package privateiq
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Errors which an *APIError can be matched against with errors.Is
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrCSRF         = errors.New("CSRF token rejected")
+	ErrConflict     = errors.New("conflict")
+	ErrUnsupported  = errors.New("unsupported by the IQ server")
+)
+
+// maxErrorBody is the most of a response body kept by an APIError
+const maxErrorBody = 512
+
+// APIError describes a request which IQ did not answer with a successful status code
+type APIError struct {
+	Method     string
+	Endpoint   string
+	StatusCode int
+	Status     string
+	// Body holds, at most, the first 512 bytes of the response
+	Body string
+}
+
+func newAPIError(req *http.Request, resp *http.Response, body []byte, host string) *APIError {
+	endpoint := strings.TrimPrefix(req.URL.String(), strings.TrimSuffix(host, "/")+"/")
+
+	if len(body) > maxErrorBody {
+		body = body[:maxErrorBody]
+	}
+
+	return &APIError{
+		Method:     req.Method,
+		Endpoint:   endpoint,
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Body:       strings.TrimSpace(string(body)),
+	}
+}
+
+func (e *APIError) Error() string {
+	status := e.Status
+	if status == "" {
+		status = fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+
+	if e.Body == "" {
+		return fmt.Sprintf("%s %s: %s", e.Method, e.Endpoint, status)
+	}
+	return fmt.Sprintf("%s %s: %s: %s", e.Method, e.Endpoint, status, e.Body)
+}
+
+// Is reports whether the status code of the error corresponds to the given sentinel error.
+// A 403 is reported as ErrCSRF too when IQ mentions the CSRF token in its response
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrCSRF:
+		return e.StatusCode == http.StatusForbidden && strings.Contains(strings.ToLower(e.Body), "csrf")
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrUnsupported:
+		return e.StatusCode == http.StatusMethodNotAllowed || e.StatusCode == http.StatusNotImplemented
+	}
+	return false
+}
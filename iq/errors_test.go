@@ -0,0 +1,71 @@
+package privateiq
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+func TestAPIErrorIs(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		target error
+		want   bool
+	}{
+		{http.StatusNotFound, "", ErrNotFound, true},
+		{http.StatusNotFound, "", ErrUnauthorized, false},
+		{http.StatusUnauthorized, "", ErrUnauthorized, true},
+		{http.StatusForbidden, "", ErrForbidden, true},
+		{http.StatusForbidden, "", ErrCSRF, false},
+		{http.StatusForbidden, "Invalid CSRF token", ErrCSRF, true},
+		{http.StatusConflict, "", ErrConflict, true},
+		{http.StatusMethodNotAllowed, "", ErrUnsupported, true},
+		{http.StatusInternalServerError, "", ErrUnsupported, false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d_%v", tt.status, tt.target), func(t *testing.T) {
+			err := fmt.Errorf("wrapped: %w", &APIError{StatusCode: tt.status, Body: tt.body})
+			if got := errors.Is(err, tt.target); got != tt.want {
+				t.Errorf("errors.Is() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIErrorFromResponse(t *testing.T) {
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path[1:] == restSessionPrivate {
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, strings.Repeat("x", maxErrorBody*2))
+	}))
+	defer mock.Close()
+
+	iq, err := publiciq.New(mock.URL, "dummy_user", "dummy_pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = DeleteOrganization(iq, "dummyOrgID")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("DeleteOrganization() error = %v, want ErrNotFound", err)
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("DeleteOrganization() error = %v, want an *APIError", err)
+	}
+	if apiErr.Method != http.MethodDelete || apiErr.Endpoint != "rest/organization/dummyOrgID" {
+		t.Errorf("unexpected request in error: %s %s", apiErr.Method, apiErr.Endpoint)
+	}
+	if len(apiErr.Body) != maxErrorBody {
+		t.Errorf("body excerpt is %d bytes, want %d", len(apiErr.Body), maxErrorBody)
+	}
+}
@@ -0,0 +1,84 @@
+package privateiq
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+const (
+	restEvaluation = "api/v2/evaluation/applications/%s"
+	// The form of the results URL an evaluation is answered with
+	restEvaluationResults = "api/v2/evaluation/applications/%s/results/%s"
+)
+
+// How often, and for how long, the results of a component evaluation are polled for
+var (
+	evaluationPollInterval = 5 * time.Second
+	evaluationTimeout      = 5 * time.Minute
+)
+
+type evaluationRequest struct {
+	Components []publiciq.Component `json:"components"`
+}
+
+type evaluationRequestResponse struct {
+	ResultID      string `json:"resultId"`
+	SubmittedDate string `json:"submittedDate"`
+	ApplicationID string `json:"applicationId"`
+	ResultsURL    string `json:"resultsUrl"`
+}
+
+// evaluateComponents behaves like publiciq.EvaluateComponents but stops polling for results when the context is done
+func evaluateComponents(ctx context.Context, iq publiciq.IQ, components []publiciq.Component, applicationID string) (*publiciq.Evaluation, error) {
+	piq := fromPublicContext(ctx, iq)
+
+	request, err := json.Marshal(evaluationRequest{Components: components})
+	if err != nil {
+		return nil, fmt.Errorf("could not build the request: %w", err)
+	}
+
+	body, _, err := piq.Post(fmt.Sprintf(restEvaluation, applicationID), bytes.NewBuffer(request))
+	if err != nil {
+		return nil, fmt.Errorf("components not evaluated: %w", err)
+	}
+
+	var results evaluationRequestResponse
+	if err = json.Unmarshal(body, &results); err != nil {
+		return nil, fmt.Errorf("could not parse evaluation response: %w", err)
+	}
+
+	timeout := time.NewTimer(evaluationTimeout)
+	defer timeout.Stop()
+	ticker := time.NewTicker(evaluationPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("stopped waiting for evaluation results: %w", ctx.Err())
+		case <-timeout.C:
+			return nil, fmt.Errorf("timed out waiting for valid evaluation results")
+		case <-ticker.C:
+			body, _, err := piq.Get(results.ResultsURL)
+			if err != nil {
+				if errors.Is(err, ErrNotFound) {
+					continue
+				}
+				return nil, fmt.Errorf("could not retrieve evaluation results: %w", err)
+			}
+
+			var eval publiciq.Evaluation
+			if err = json.Unmarshal(body, &eval); err != nil {
+				return nil, fmt.Errorf("could not parse evaluation results: %w", err)
+			}
+
+			return &eval, nil
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package privateiq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+// MatchState is how IQ matched a component to the ones it knows
+type MatchState string
+
+// The match states of components
+const (
+	MatchExact   MatchState = "exact"
+	MatchSimilar MatchState = "similar"
+	MatchUnknown MatchState = "unknown"
+)
+
+// ThreatLevel is the severity of a policy violation, from 0 to 10
+type ThreatLevel int
+
+// Category returns how IQ groups the threat level: critical, severe, moderate, low or none
+func (t ThreatLevel) Category() string {
+	switch {
+	case t >= 8:
+		return "critical"
+	case t >= 4:
+		return "severe"
+	case t >= 2:
+		return "moderate"
+	case t >= 1:
+		return "low"
+	}
+	return "none"
+}
+
+// The fields the Firewall report can be sorted by
+const (
+	FirewallSortThreatLevel = "threatLevel"
+	FirewallSortComponent   = "componentDisplayText"
+	FirewallSortPolicy      = "policyName"
+)
+
+// DefaultFirewallPageSize is the number of components per page when a FirewallQuery does not set it
+const DefaultFirewallPageSize = 100
+
+// FirewallQuery filters, sorts and pages the Firewall report of a repository.
+// IQ does so itself from the version the compatibility table sets for QueryFirewallReport, given the parameters of values.
+// The package filters the report of older versions, and the pages of servers which ignore a filter, itself
+type FirewallQuery struct {
+	QuarantinedOnly bool
+	// Waived only keeps the waived components if true, and those not waived if false
+	Waived *bool
+	// PolicyName only keeps the components violating the named policy
+	PolicyName string
+	// MinThreatLevel and MaxThreatLevel bound the threat level of the components kept. Zero leaves them unbounded
+	MinThreatLevel, MaxThreatLevel ThreatLevel
+	// Page is counted from 1
+	Page     int
+	PageSize int
+	// SortBy is one of the FirewallSort fields
+	SortBy    string
+	Ascending bool
+}
+
+// values returns the parameters of the paged Firewall report
+func (q FirewallQuery) values() url.Values {
+	v := url.Values{}
+	v.Set("page", strconv.Itoa(q.Page))
+	v.Set("pageSize", strconv.Itoa(q.PageSize))
+	if q.QuarantinedOnly {
+		v.Set("quarantined", "true")
+	}
+	if q.Waived != nil {
+		v.Set("waived", strconv.FormatBool(*q.Waived))
+	}
+	if q.PolicyName != "" {
+		v.Set("policyName", q.PolicyName)
+	}
+	if q.MinThreatLevel > 0 {
+		v.Set("minThreatLevel", strconv.Itoa(int(q.MinThreatLevel)))
+	}
+	if q.MaxThreatLevel > 0 {
+		v.Set("maxThreatLevel", strconv.Itoa(int(q.MaxThreatLevel)))
+	}
+	if q.SortBy != "" {
+		v.Set("sortBy", q.SortBy)
+		v.Set("asc", strconv.FormatBool(q.Ascending))
+	}
+	return v
+}
+
+// matches reports whether the component passes the filters of the query
+func (q FirewallQuery) matches(c FirewallComponent) bool {
+	switch {
+	case q.QuarantinedOnly && !c.Quarantined,
+		q.Waived != nil && c.Waived != *q.Waived,
+		q.PolicyName != "" && c.PolicyName != q.PolicyName,
+		q.MinThreatLevel > 0 && c.ThreatLevel < q.MinThreatLevel,
+		q.MaxThreatLevel > 0 && c.ThreatLevel > q.MaxThreatLevel:
+		return false
+	}
+	return true
+}
+
+// FirewallPage is a page of the Firewall report of a repository
+type FirewallPage struct {
+	Page     int `json:"page"`
+	PageSize int `json:"pageSize"`
+	// Total is the number of components IQ reports, which includes those of any filter it ignored
+	Total   int                 `json:"total"`
+	Results []FirewallComponent `json:"results"`
+
+	// fetched is the number of components IQ returned, before the package filtered them
+	fetched int
+}
+
+// last reports whether there are no pages after this one
+func (p *FirewallPage) last() bool {
+	return p.fetched < p.PageSize || p.Page*p.PageSize >= p.Total
+}
+
+// filter drops the results which do not pass the filters of the query
+func (p *FirewallPage) filter(query FirewallQuery) {
+	p.fetched = len(p.Results)
+	results := p.Results[:0]
+	for _, c := range p.Results {
+		if query.matches(c) {
+			results = append(results, c)
+		}
+	}
+	p.Results = results
+}
+
+// QueryFirewallReport returns a page of the components in a Firewalled proxy which pass the filters of the query.
+// Servers which do not page the report return every component in the first page, filtered by the package
+func QueryFirewallReport(iq publiciq.IQ, repoid string, query FirewallQuery) (*FirewallPage, error) {
+	return QueryFirewallReportContext(context.Background(), iq, repoid, query)
+}
+
+// QueryFirewallReportContext returns a page of the components in a Firewalled proxy which pass the filters of the query.
+// Servers which do not page the report return every component in the first page, filtered by the package
+func QueryFirewallReportContext(ctx context.Context, iq publiciq.IQ, repoid string, query FirewallQuery) (*FirewallPage, error) {
+	piq := fromPublicContext(ctx, iq)
+
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize < 1 {
+		query.PageSize = DefaultFirewallPageSize
+	}
+
+	variant, err := piq.variant("QueryFirewallReport")
+	if err != nil {
+		return nil, err
+	}
+
+	// Variants given the query as a second parameter page the report, the others return it whole
+	endpoint := fmt.Sprintf(variant.Endpoint, repoid)
+	if len(formatVerb.FindAllString(variant.Endpoint, -1)) > 1 {
+		endpoint = fmt.Sprintf(variant.Endpoint, repoid, query.values().Encode())
+	}
+
+	body, _, err := piq.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve firewall report of repository '%s': %w", repoid, err)
+	}
+
+	page := new(FirewallPage)
+	if len(body) > 0 && body[0] == '[' {
+		var all []FirewallComponent
+		if err = json.Unmarshal(body, &all); err != nil {
+			return nil, fmt.Errorf("could not parse firewall report of repository '%s': %w", repoid, err)
+		}
+
+		page.Results = make([]FirewallComponent, 0, len(all))
+		if query.Page == 1 {
+			page.Results = all
+		}
+		page.filter(query)
+		page.Page, page.PageSize, page.Total = query.Page, len(page.Results), len(page.Results)
+
+		return page, nil
+	}
+
+	if err = json.Unmarshal(body, page); err != nil {
+		return nil, fmt.Errorf("could not parse firewall report of repository '%s': %w", repoid, err)
+	}
+	page.filter(query)
+
+	return page, nil
+}
+
+// FirewallIterator walks the components of the Firewall report of a repository, fetching a page at a time as needed.
+// Next must be called before each component, and Err checked once it returns false:
+//
+//	it := NewFirewallIterator(ctx, iq, "maven-central", FirewallQuery{QuarantinedOnly: true})
+//	for it.Next() {
+//		fmt.Println(it.Component().ComponentDisplayText)
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type FirewallIterator struct {
+	ctx    context.Context
+	iq     publiciq.IQ
+	repoid string
+	query  FirewallQuery
+
+	page  *FirewallPage
+	index int
+	err   error
+}
+
+// NewFirewallIterator creates an iterator over the components passing the filters of the query,
+// starting from its page
+func NewFirewallIterator(ctx context.Context, iq publiciq.IQ, repoid string, query FirewallQuery) *FirewallIterator {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	return &FirewallIterator{ctx: ctx, iq: iq, repoid: repoid, query: query}
+}
+
+// Next advances to the next component, fetching the next page when the current one is exhausted.
+// It returns false once there are no more components, or an error occurred
+func (it *FirewallIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	// Pages whose components were all filtered by the package are skipped
+	for {
+		if it.page != nil && it.index+1 < len(it.page.Results) {
+			it.index++
+			return true
+		}
+		if it.page != nil && it.page.last() {
+			return false
+		}
+
+		if it.page != nil {
+			it.query.Page++
+		}
+		it.page, it.err = QueryFirewallReportContext(it.ctx, it.iq, it.repoid, it.query)
+		if it.err != nil {
+			return false
+		}
+		it.index = -1
+	}
+}
+
+// Component returns the current component
+func (it *FirewallIterator) Component() FirewallComponent {
+	return it.page.Results[it.index]
+}
+
+// Total returns the number of components passing the filters, once the first page was fetched
+func (it *FirewallIterator) Total() int {
+	if it.page == nil {
+		return 0
+	}
+	return it.page.Total
+}
+
+// Err returns the error which stopped the iteration, if any
+func (it *FirewallIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,147 @@
+package privateiq
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hokiegeek/gonexus-private/iq/privateiqtest"
+)
+
+func firewallTestServer() *privateiqtest.Server {
+	server := privateiqtest.NewServer()
+
+	components := make([]privateiqtest.FirewallComponent, 0, 250)
+	for i := 0; i < 250; i++ {
+		c := privateiqtest.FirewallComponent{
+			ComponentDisplayText: fmt.Sprintf("component%03d", i),
+			Hash:                 fmt.Sprintf("hash%03d", i),
+			MatchState:           "exact",
+			ThreatLevel:          i % 11,
+			PolicyName:           "Security-Medium",
+			Quarantined:          i%2 == 0,
+			Waived:               i%5 == 0,
+		}
+		if c.ThreatLevel >= 8 {
+			c.PolicyName = "Security-Critical"
+		}
+		components = append(components, c)
+	}
+	server.SetFirewallComponents("maven-central", components)
+
+	return server
+}
+
+// ignoring is a transport which drops the given parameters from the query of requests, as if the server ignored them
+type ignoring []string
+
+func (params ignoring) RoundTrip(req *http.Request) (*http.Response, error) {
+	ignored := req.Clone(req.Context())
+	query := ignored.URL.Query()
+	for _, p := range params {
+		query.Del(p)
+	}
+	ignored.URL.RawQuery = query.Encode()
+	return http.DefaultTransport.RoundTrip(ignored)
+}
+
+func TestQueryFirewallReport(t *testing.T) {
+	server := firewallTestServer()
+	defer server.Close()
+
+	notWaived := false
+	query := FirewallQuery{
+		QuarantinedOnly: true,
+		Waived:          &notWaived,
+		PolicyName:      "Security-Critical",
+		MinThreatLevel:  9,
+		PageSize:        5,
+		SortBy:          FirewallSortThreatLevel,
+	}
+
+	page, err := QueryFirewallReport(server.IQ(), "maven-central", query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Page != 1 || len(page.Results) != 5 || page.Total <= 5 {
+		t.Fatalf("got page %d with %d of %d components", page.Page, len(page.Results), page.Total)
+	}
+	for i, c := range page.Results {
+		if !c.Quarantined || c.Waived || c.ThreatLevel < 9 || c.ThreatLevel.Category() != "critical" || c.MatchState != MatchExact {
+			t.Errorf("component does not pass the filters: %+v", c)
+		}
+		if i > 0 && c.ThreatLevel > page.Results[i-1].ThreatLevel {
+			t.Errorf("components are not sorted by descending threat level: %v", page.Results)
+		}
+	}
+	if !strings.Contains(server.Requests()[len(server.Requests())-1], "quarantined=true") {
+		t.Errorf("the filters were not sent: %s", server.Requests())
+	}
+}
+
+func TestFirewallIterator(t *testing.T) {
+	server := firewallTestServer()
+	defer server.Close()
+
+	it := NewFirewallIterator(context.Background(), server.IQ(), "maven-central", FirewallQuery{QuarantinedOnly: true, PageSize: 50})
+	var n int
+	for it.Next() {
+		if !it.Component().Quarantined {
+			t.Errorf("component is not quarantined: %+v", it.Component())
+		}
+		n++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != 125 || it.Total() != 125 {
+		t.Errorf("iterated over %d of %d components, want 125", n, it.Total())
+	}
+
+	var pages int
+	for _, r := range server.Requests() {
+		if strings.HasPrefix(r, "GET rest/repositories/maven-central/report/details?") {
+			pages++
+		}
+	}
+	if pages != 3 {
+		t.Errorf("fetched %d pages, want 3", pages)
+	}
+
+	// Pages of servers which ignore a filter are filtered by the package
+	ignored := FromPublic(server.IQ(), WithTransport(ignoring{"quarantined"}))
+	it = NewFirewallIterator(context.Background(), ignored, "maven-central", FirewallQuery{QuarantinedOnly: true, PageSize: 50})
+	for n = 0; it.Next(); n++ {
+		if !it.Component().Quarantined {
+			t.Errorf("component is not quarantined: %+v", it.Component())
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != 125 {
+		t.Errorf("iterated over %d components of ignored filters, want 125", n)
+	}
+
+	// Versions which do not page the report are not sent the query, and are filtered by the package
+	server.SetVersion("1.98.0-01")
+	it = NewFirewallIterator(context.Background(), server.IQ(), "maven-central", FirewallQuery{QuarantinedOnly: true, PageSize: 50})
+	for n = 0; it.Next(); n++ {
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != 125 {
+		t.Errorf("iterated over %d unpaged components, want 125", n)
+	}
+	if last := server.Requests()[len(server.Requests())-1]; last != "GET rest/repositories/maven-central/report/details" {
+		t.Errorf("the unpaged report was requested with %s", last)
+	}
+
+	it = NewFirewallIterator(context.Background(), server.IQ(), "missing", FirewallQuery{})
+	if it.Next() || it.Err() == nil {
+		t.Error("iterating over a missing repository did not fail")
+	}
+}
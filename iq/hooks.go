@@ -0,0 +1,180 @@
+package privateiq
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestInfo describes a request about to be sent to IQ
+type RequestInfo struct {
+	Method string
+	// Endpoint is the template of the endpoint, such as rest/organization/%s, which is suitable as a metric label.
+	// Endpoints the package does not know about are given as is
+	Endpoint string
+	// Path is the endpoint the request is sent to, along with its query
+	Path string
+	// Attempt counts the times the request was sent, starting with 1, when it is retried
+	Attempt   int
+	BytesSent int64
+}
+
+// ResponseInfo describes the outcome of a request sent to IQ
+type ResponseInfo struct {
+	RequestInfo
+	// StatusCode is zero when no response was received
+	StatusCode    int
+	Latency       time.Duration
+	BytesReceived int64
+	// Err is the error the request failed with, which is an *APIError for unsuccessful responses
+	Err error
+}
+
+// Hook observes the requests sent to IQ, for logging, metrics or tracing.
+// BeforeRequest may return a derived context, such as one holding a trace span, which is the one given to AfterRequest
+type Hook interface {
+	BeforeRequest(ctx context.Context, info RequestInfo) context.Context
+	AfterRequest(ctx context.Context, info ResponseInfo)
+}
+
+// HookFuncs is a Hook made of functions, either of which may be nil
+type HookFuncs struct {
+	Before func(ctx context.Context, info RequestInfo) context.Context
+	After  func(ctx context.Context, info ResponseInfo)
+}
+
+// BeforeRequest calls the Before function
+func (h HookFuncs) BeforeRequest(ctx context.Context, info RequestInfo) context.Context {
+	if h.Before == nil {
+		return ctx
+	}
+	return h.Before(ctx, info)
+}
+
+// AfterRequest calls the After function
+func (h HookFuncs) AfterRequest(ctx context.Context, info ResponseInfo) {
+	if h.After != nil {
+		h.After(ctx, info)
+	}
+}
+
+// WithHooks adds hooks which are invoked before and after every request of the instance, including retries
+func WithHooks(hooks ...Hook) Option {
+	return func(iq *privateiq) {
+		iq.hooks = append(append([]Hook(nil), iq.hooks...), hooks...)
+	}
+}
+
+// Endpoints which are requested without going through the compatibility table
+var templates = []string{
+	restSessionPrivate,
+	restProductVersion,
+	restEvaluation,
+	restEvaluationResults,
+	restOrganizations,
+	restApplications,
+	restApplicationByPublicID,
+	restApplication,
+	restReportInfos,
+	restReportRaw,
+}
+
+var formatVerb = regexp.MustCompile(`%[a-z]`)
+
+var templatePatterns = struct {
+	sync.Mutex
+	compiled map[string]*regexp.Regexp
+}{compiled: make(map[string]*regexp.Regexp)}
+
+func templatePattern(template string) *regexp.Regexp {
+	templatePatterns.Lock()
+	defer templatePatterns.Unlock()
+
+	re, ok := templatePatterns.compiled[template]
+	if !ok {
+		base, query := template, ""
+		switch {
+		// A query given whole, as the last parameter, may hold any number of parameters
+		case strings.HasSuffix(template, "?%s"):
+			base, query = strings.TrimSuffix(template, "?%s"), `\?.*`
+		// The query of an endpoint whose template has none, such as paging, is not part of the template
+		case !strings.Contains(template, "?"):
+			query = `(?:\?.*)?`
+		}
+		pattern := "^" + formatVerb.ReplaceAllString(regexp.QuoteMeta(base), "[^/?&]+") + query
+		re = regexp.MustCompile(pattern + "$")
+		templatePatterns.compiled[template] = re
+	}
+	return re
+}
+
+// template returns the template of the given endpoint, or the endpoint itself if it matches none.
+// When several match, such as with and without a query, the longest is the most specific
+func (iq privateiq) template(endpoint string) string {
+	var best string
+	match := func(t string) {
+		if len(t) > len(best) && templatePattern(t).MatchString(endpoint) {
+			best = t
+		}
+	}
+
+	for _, variants := range iq.variants {
+		for _, v := range variants {
+			match(v.Endpoint)
+		}
+	}
+	for _, variants := range compatibility {
+		for _, v := range variants {
+			match(v.Endpoint)
+		}
+	}
+	for _, t := range templates {
+		match(t)
+	}
+
+	if best == "" {
+		return endpoint
+	}
+	return best
+}
+
+// observe invokes the BeforeRequest hooks, returning the request bound to the context they returned
+// and the function which invokes the AfterRequest hooks
+func (iq privateiq) observe(request *http.Request, attempt int) (*http.Request, func(*http.Response, int64, error)) {
+	if len(iq.hooks) == 0 {
+		return request, func(*http.Response, int64, error) {}
+	}
+
+	path := strings.TrimPrefix(request.URL.String(), strings.TrimSuffix(iq.Host, "/")+"/")
+	info := RequestInfo{
+		Method:    request.Method,
+		Endpoint:  iq.template(path),
+		Path:      path,
+		Attempt:   attempt,
+		BytesSent: request.ContentLength,
+	}
+
+	ctx := request.Context()
+	for _, h := range iq.hooks {
+		ctx = h.BeforeRequest(ctx, info)
+	}
+	start := time.Now()
+
+	return request.WithContext(ctx), func(resp *http.Response, received int64, err error) {
+		result := ResponseInfo{
+			RequestInfo:   info,
+			Latency:       time.Since(start),
+			BytesReceived: received,
+			Err:           err,
+		}
+		if resp != nil {
+			result.StatusCode = resp.StatusCode
+		}
+		for _, h := range iq.hooks {
+			h.AfterRequest(ctx, result)
+		}
+	}
+}
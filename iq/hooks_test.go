@@ -0,0 +1,148 @@
+package privateiq
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hokiegeek/gonexus-private/iq/privateiqtest"
+)
+
+type traceKey struct{}
+
+func TestHooks(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+	org := server.AddOrganization("foobar", "")
+
+	var mu sync.Mutex
+	var observed []ResponseInfo
+	hook := HookFuncs{
+		Before: func(ctx context.Context, info RequestInfo) context.Context {
+			return context.WithValue(ctx, traceKey{}, info.Path)
+		},
+		After: func(ctx context.Context, info ResponseInfo) {
+			if ctx.Value(traceKey{}) != info.Path {
+				t.Errorf("the context returned by BeforeRequest was not given to AfterRequest")
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			observed = append(observed, info)
+		},
+	}
+	iq := FromPublic(server.IQ(), WithHooks(hook))
+
+	if err := DeleteOrganization(iq, org.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteOrganization(iq, "missing"); err == nil {
+		t.Fatal("expected an error deleting a missing organization")
+	}
+
+	want := []struct {
+		method, endpoint, path string
+		status                 int
+	}{
+		{http.MethodGet, restSessionPrivate, restSessionPrivate, http.StatusOK},
+		{http.MethodDelete, restOrganizationPrivate, "rest/organization/" + org.ID, http.StatusNoContent},
+		{http.MethodDelete, restOrganizationPrivate, "rest/organization/missing", http.StatusNotFound},
+	}
+	if len(observed) != len(want) {
+		t.Fatalf("observed %d requests, want %d: %v", len(observed), len(want), observed)
+	}
+	for i, w := range want {
+		got := observed[i]
+		if got.Method != w.method || got.Endpoint != w.endpoint || got.Path != w.path || got.StatusCode != w.status {
+			t.Errorf("request %d: got %s %s (%s) %d, want %s %s (%s) %d",
+				i, got.Method, got.Endpoint, got.Path, got.StatusCode, w.method, w.endpoint, w.path, w.status)
+		}
+		if got.Attempt != 1 || got.Latency <= 0 {
+			t.Errorf("request %d: unexpected attempt %d or latency %s", i, got.Attempt, got.Latency)
+		}
+	}
+
+	var apiErr *APIError
+	if last := observed[len(observed)-1]; !errors.As(last.Err, &apiErr) || last.BytesReceived == 0 {
+		t.Errorf("the failed request was not reported with its error and body: %v", last)
+	}
+}
+
+func TestHooksLeaveQueryOut(t *testing.T) {
+	server := firewallTestServer()
+	defer server.Close()
+
+	var observed []RequestInfo
+	iq := FromPublic(server.IQ(), WithHooks(HookFuncs{Before: func(ctx context.Context, info RequestInfo) context.Context {
+		observed = append(observed, info)
+		return ctx
+	}}))
+
+	for page := 1; page <= 2; page++ {
+		if _, err := QueryFirewallReport(iq, "maven-central", FirewallQuery{Page: page, PolicyName: "Security-Critical"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var queries int
+	for _, info := range observed {
+		if !strings.HasPrefix(info.Path, "rest/repositories/") {
+			continue
+		}
+		queries++
+		if info.Endpoint != restFirewallPaged || !strings.Contains(info.Path, "?") {
+			t.Errorf("%s was observed as endpoint %s", info.Path, info.Endpoint)
+		}
+	}
+	if queries != 2 {
+		t.Errorf("observed %d queries of the report, want 2: %v", queries, observed)
+	}
+}
+
+func TestTemplateKnowsEveryEndpoint(t *testing.T) {
+	endpoints := []string{
+		restSessionPrivate,
+		restProductVersion,
+		restEvaluation,
+		restEvaluationResults,
+		restOrganizations,
+		restApplications,
+		restApplication,
+		restApplicationByPublicID,
+		restReportInfos,
+		restReportRaw,
+		restOrganizationPrivate,
+		restOrganizationsPrivate,
+		restOrganizationMove,
+		restApplicationMove,
+		restFirewallPrivate,
+		restFirewallPaged,
+		restSupportZip,
+		restWebhooks,
+		restAutoApps,
+		restSystemNotice,
+		restMonitoringOrg,
+		restMonitoringApp,
+		restMonitoringTrigger,
+		restLicense,
+		restReportReevaluate,
+		restLabelComponentOrg,
+		restVulnDetails,
+		restPolicyExportPrivate,
+		restPolicyImportPrivate,
+		restWaiversForApplication,
+	}
+
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	iq := FromPublic(server.IQ()).(*privateiq)
+	for _, endpoint := range endpoints {
+		sample := strings.NewReplacer("%s", "a1b2c3d4", "%t", "true").Replace(endpoint)
+		if got := iq.template(sample); got != endpoint {
+			t.Errorf("template(%q) = %q, want %q", sample, got, endpoint)
+		}
+	}
+}
@@ -1,20 +1,160 @@
 package privateiq
 
 import (
+	"context"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"net/http/httputil"
+	"sync"
+	"time"
 
 	nexus "github.com/sonatype-nexus-community/gonexus"
 	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
 )
 
-const restSessionPrivate = "rest/user/session"
+const (
+	restSessionPrivate = "rest/user/session"
+
+	csrfCookie = "CLM-CSRF-TOKEN"
+	csrfHeader = "X-CSRF-TOKEN"
+)
+
+// session holds the cookies of an established private session so that they can be shared by every request
+type session struct {
+	sync.RWMutex
+	established bool
+	// injected sessions were established outside of the package and cannot be established again
+	injected bool
+	cookies  []*http.Cookie
+	csrf     string
+}
+
+// get returns the session cookies, establishing the session with the given function if needed
+func (s *session) get(establish func() ([]*http.Cookie, error)) ([]*http.Cookie, string, error) {
+	s.RLock()
+	if s.established {
+		defer s.RUnlock()
+		return s.cookies, s.csrf, nil
+	}
+	s.RUnlock()
+
+	s.Lock()
+	defer s.Unlock()
+	if s.established {
+		return s.cookies, s.csrf, nil
+	}
+
+	cookies, err := establish()
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.cookies = cookies
+	s.csrf = ""
+	for _, cookie := range cookies {
+		if cookie.Name == csrfCookie {
+			s.csrf = cookie.Value
+		}
+	}
+	s.established = true
+
+	return s.cookies, s.csrf, nil
+}
+
+// expire drops the session if it is still the one identified by the given CSRF token.
+// Requests which failed with an older token do not expire a session which was already renewed.
+func (s *session) expire(csrf string) {
+	s.Lock()
+	defer s.Unlock()
+	if s.established && !s.injected && s.csrf == csrf {
+		s.established = false
+		s.cookies = nil
+		s.csrf = ""
+	}
+}
+
+func applySession(req *http.Request, cookies []*http.Cookie, csrf string) {
+	req.Header.Del("Cookie")
+	req.Header.Del(csrfHeader)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	if csrf != "" {
+		req.Header.Set(csrfHeader, csrf)
+	}
+}
+
+// IQ is the interface implemented by the instances returned by FromPublic.
+// On top of the public interface, it allows requests to be bound to a context
+type IQ interface {
+	publiciq.IQ
+	NewRequestContext(ctx context.Context, method, endpoint string, payload io.Reader) (*http.Request, error)
+	GetContext(ctx context.Context, endpoint string) ([]byte, *http.Response, error)
+	PostContext(ctx context.Context, endpoint string, payload io.Reader) ([]byte, *http.Response, error)
+	PutContext(ctx context.Context, endpoint string, payload io.Reader) ([]byte, *http.Response, error)
+	DelContext(ctx context.Context, endpoint string) (*http.Response, error)
+}
 
 // Defines a new IQ instance which provides overrides to transparently allow access to private APIs
 type privateiq struct {
 	nexus.DefaultClient
 	// publiciq.IQ
-	pub publiciq.IQ
+	pub      publiciq.IQ
+	sess     *session
+	version  *serverVersion
+	variants map[string][]EndpointVariant
+	ctx      context.Context
+	client   *http.Client
+	retry    RetryPolicy
+	limiter  *Limiter
+	hooks    []Hook
+	// tempPrefix names the temporary organizations and applications created for ad-hoc evaluations
+	tempPrefix string
+	// streaming leaves the body of successful responses unread, for the caller to read and close
+	streaming bool
+}
+
+// Option configures the instance returned by FromPublic
+type Option func(*privateiq)
+
+// withContext returns a copy of the instance, sharing its session, whose requests are bound to the given context
+func (iq *privateiq) withContext(ctx context.Context) *privateiq {
+	c := *iq
+	c.ctx = ctx
+	return &c
+}
+
+// stream returns a copy of the instance whose successful responses are returned with their body unread
+func (iq *privateiq) stream() *privateiq {
+	c := *iq
+	c.streaming = true
+	return &c
+}
+
+func (iq privateiq) context() context.Context {
+	if iq.ctx == nil {
+		return context.Background()
+	}
+	return iq.ctx
+}
+
+func (iq privateiq) establishSession() ([]*http.Cookie, error) {
+	req, err := iq.pub.NewRequest(http.MethodGet, restSessionPrivate, nil)
+	if err != nil {
+		return nil, err
+	}
+	iq.authenticate(req)
+
+	// The session is established before streaming the request which needed it
+	iq.streaming = false
+	_, resp, err := iq.send(req.WithContext(iq.context()))
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Cookies(), nil
 }
 
 // NewRequest creates an http.Request object with private session
@@ -23,20 +163,177 @@ func (iq privateiq) NewRequest(method, endpoint string, payload io.Reader) (*htt
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(iq.context())
+	iq.authenticate(req)
 
-	_, resp, err := iq.pub.Get(restSessionPrivate)
+	cookies, csrf, err := iq.sess.get(iq.establishSession)
 	if err != nil {
 		return nil, err
 	}
+	applySession(req, cookies, csrf)
 
-	for _, cookie := range resp.Cookies() {
-		req.AddCookie(cookie)
-		if cookie.Name == "CLM-CSRF-TOKEN" {
-			req.Header.Add("X-CSRF-TOKEN", cookie.Value)
+	return req, nil
+}
+
+// NewRequestContext creates an http.Request object with private session which is bound to the given context
+func (iq privateiq) NewRequestContext(ctx context.Context, method, endpoint string, payload io.Reader) (*http.Request, error) {
+	return iq.withContext(ctx).NewRequest(method, endpoint, payload)
+}
+
+// send performs the http.Request, retrying it as allowed by the retry policy when it fails transiently
+func (iq privateiq) send(request *http.Request) ([]byte, *http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		body, resp, err := iq.sendOnce(request, attempt)
+		if !transient(request, resp, err) || !iq.retry.retryable(request, attempt) {
+			return body, resp, err
+		}
+
+		if serr := sleep(request.Context(), iq.retry.backoff(attempt, resp)); serr != nil {
+			return body, resp, err
+		}
+
+		if request.GetBody != nil {
+			retry := request.Clone(request.Context())
+			if retry.Body, err = request.GetBody(); err != nil {
+				return nil, nil, err
+			}
+			request = retry
 		}
 	}
+}
 
-	return req, nil
+func transient(request *http.Request, resp *http.Response, err error) bool {
+	if resp != nil {
+		return isTransientStatus(resp.StatusCode)
+	}
+	return err != nil && request.Context().Err() == nil
+}
+
+// sendOnce performs the http.Request, as the given attempt, and reads the body of the response.
+// Any response without a 2xx status code is returned as an *APIError
+func (iq privateiq) sendOnce(request *http.Request, attempt int) ([]byte, *http.Response, error) {
+	if iq.Debug {
+		dump, _ := httputil.DumpRequest(request, true)
+		fmt.Printf("%q\n", dump)
+	}
+
+	release, err := iq.limiter.acquire(request.Context())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	request, observed := iq.observe(request, attempt)
+
+	// The time limit of the client would cut a streamed body off part way, so only the context bounds it
+	client := iq.client
+	if iq.streaming && client.Timeout > 0 {
+		unlimited := *client
+		unlimited.Timeout = 0
+		client = &unlimited
+	}
+
+	resp, err := client.Do(request)
+	if err != nil {
+		observed(nil, 0, err)
+		release()
+		return nil, nil, err
+	}
+
+	// The body of a streamed response is left for the caller to read, which releases the limiter once closed
+	if iq.streaming && resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		resp.Body = &streamBody{ReadCloser: resp.Body, done: func(n int64, err error) {
+			observed(resp, n, err)
+			release()
+		}}
+		return nil, resp, nil
+	}
+	defer release()
+
+	body, err := readResponse(request, resp, iq.Host)
+	observed(resp, int64(len(body)), err)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return body, resp, nil
+}
+
+// readResponse reads the body of the response, even an unsuccessful one which is returned as an *APIError
+func readResponse(request *http.Request, resp *http.Response, host string) ([]byte, error) {
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return body, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return body, newAPIError(request, resp, body, host)
+	}
+
+	return body, nil
+}
+
+// streamBody is the body of a streamed response, which reports how much of it was read once closed
+type streamBody struct {
+	io.ReadCloser
+	read int64
+	err  error
+	once sync.Once
+	done func(n int64, err error)
+}
+
+func (b *streamBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
+	return n, err
+}
+
+func (b *streamBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.done(b.read, b.err) })
+	return err
+}
+
+// Do performs the http.Request. If IQ rejects the private session or its CSRF token,
+// the session is established again and the request is retried once.
+// Unsuccessful responses are returned as an *APIError
+func (iq privateiq) Do(request *http.Request) ([]byte, *http.Response, error) {
+	body, resp, err := iq.send(request)
+	if resp == nil || (resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden) {
+		return body, resp, err
+	}
+
+	if request.Body != nil && request.GetBody == nil {
+		return body, resp, err
+	}
+
+	iq.sess.RLock()
+	injected := iq.sess.injected
+	iq.sess.RUnlock()
+	if injected {
+		return body, resp, err
+	}
+
+	iq.sess.expire(request.Header.Get(csrfHeader))
+
+	cookies, csrf, serr := iq.sess.get(iq.establishSession)
+	if serr != nil {
+		return body, resp, err
+	}
+
+	retry := request.Clone(request.Context())
+	if request.GetBody != nil {
+		if retry.Body, serr = request.GetBody(); serr != nil {
+			return body, resp, err
+		}
+	}
+	applySession(retry, cookies, csrf)
+
+	return iq.send(retry)
 }
 
 func (iq privateiq) http(method, endpoint string, payload io.Reader) ([]byte, *http.Response, error) {
@@ -69,10 +366,60 @@ func (iq privateiq) Del(endpoint string) (resp *http.Response, err error) {
 	return
 }
 
-// FromPublic wraps a privateiq instance around a public one
-func FromPublic(iq publiciq.IQ) publiciq.IQ {
+// GetContext performs an HTTP GET against the indicated endpoint which is bound to the given context
+func (iq privateiq) GetContext(ctx context.Context, endpoint string) ([]byte, *http.Response, error) {
+	return iq.withContext(ctx).Get(endpoint)
+}
+
+// PostContext performs an HTTP POST against the indicated endpoint which is bound to the given context
+func (iq privateiq) PostContext(ctx context.Context, endpoint string, payload io.Reader) ([]byte, *http.Response, error) {
+	return iq.withContext(ctx).Post(endpoint, payload)
+}
+
+// PutContext performs an HTTP PUT against the indicated endpoint which is bound to the given context
+func (iq privateiq) PutContext(ctx context.Context, endpoint string, payload io.Reader) ([]byte, *http.Response, error) {
+	return iq.withContext(ctx).Put(endpoint, payload)
+}
+
+// DelContext performs an HTTP DELETE against the indicated endpoint which is bound to the given context
+func (iq privateiq) DelContext(ctx context.Context, endpoint string) (*http.Response, error) {
+	return iq.withContext(ctx).Del(endpoint)
+}
+
+// FromPublic wraps a privateiq instance around a public one, configured with the given options.
+// The returned instance establishes its private session once and shares it, safely, across goroutines.
+// Wrapping an instance which was already returned by FromPublic, without options, returns it unchanged;
+// with options it returns a reconfigured copy which keeps sharing the session
+func FromPublic(iq publiciq.IQ, opts ...Option) IQ {
+	priv := wrap(iq)
+	if len(opts) == 0 {
+		return priv
+	}
+
+	c := *priv
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return &c
+}
+
+// fromPublicContext wraps the given instance and binds all of its requests to the given context.
+// Passing the result to the publiciq functions also binds their requests to the context
+func fromPublicContext(ctx context.Context, iq publiciq.IQ) *privateiq {
+	return wrap(iq).withContext(ctx)
+}
+
+func wrap(iq publiciq.IQ) *privateiq {
+	if priv, ok := iq.(*privateiq); ok {
+		return priv
+	}
+
 	priv := new(privateiq)
 	priv.pub = iq
+	priv.sess = new(session)
+	priv.version = new(serverVersion)
+	priv.limiter = DefaultLimiter
+	priv.client = &http.Client{Timeout: 30 * time.Second}
 	priv.Host = iq.Info().Host
 	priv.Username = iq.Info().Username
 	priv.Password = iq.Info().Password
@@ -2,8 +2,8 @@ package privateiq
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
 
 	nexusiq "github.com/sonatype-nexus-community/gonexus/iq"
 	// nexusiq "github.com/sonatype-nexus-community/gonexus/iq"
@@ -25,8 +25,18 @@ type IqComponentLabel struct {
 }
 
 func GetAllComponentLabels(iq nexusiq.IQ) ([]IqComponentLabel, error) {
-	endpoint := fmt.Sprintf(restLabelComponentOrg, "ROOT_ORGANIZATION_ID")
-	body, _, err := FromPublic(iq).Get(endpoint)
+	return GetAllComponentLabelsContext(context.Background(), iq)
+}
+
+func GetAllComponentLabelsContext(ctx context.Context, iq nexusiq.IQ) ([]IqComponentLabel, error) {
+	piq := fromPublicContext(ctx, iq)
+
+	endpoint, err := piq.endpoint("GetAllComponentLabels", "ROOT_ORGANIZATION_ID")
+	if err != nil {
+		return nil, err
+	}
+
+	body, _, err := piq.Get(endpoint)
 	if err != nil {
 		return nil, err
 	}
@@ -41,13 +51,22 @@ func GetAllComponentLabels(iq nexusiq.IQ) ([]IqComponentLabel, error) {
 }
 
 func CreateComponentLabel(iq nexusiq.IQ, organization, label, description, color string) error {
+	return CreateComponentLabelContext(context.Background(), iq, organization, label, description, color)
+}
+
+func CreateComponentLabelContext(ctx context.Context, iq nexusiq.IQ, organization, label, description, color string) error {
 	req := &IqComponentLabel{OwnerID: organization, Label: label, Description: description, Color: color}
 	buf, err := json.Marshal(req)
 	if err != nil {
 		return err
 	}
-	endpoint := fmt.Sprintf(restLabelComponentOrg, "ROOT_ORGANIZATION_ID")
-	_, _, err = FromPublic(iq).Post(endpoint, bytes.NewBuffer(buf))
+	piq := fromPublicContext(ctx, iq)
+	endpoint, err := piq.endpoint("CreateComponentLabel", "ROOT_ORGANIZATION_ID")
+	if err != nil {
+		return err
+	}
+
+	_, _, err = piq.Post(endpoint, bytes.NewBuffer(buf))
 	return err
 }
 
@@ -2,11 +2,11 @@ package privateiq
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"mime/multipart"
-	"net/http"
 
 	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
 )
@@ -28,40 +28,65 @@ type NexusLicense struct {
 
 // InstallLicense allows for an IQ license to be installed
 func InstallLicense(iq publiciq.IQ, license io.Reader) error {
+	return InstallLicenseContext(context.Background(), iq, license)
+}
+
+// InstallLicenseContext allows for an IQ license to be installed
+func InstallLicenseContext(ctx context.Context, iq publiciq.IQ, license io.Reader) error {
 	var b bytes.Buffer
 	w := multipart.NewWriter(&b)
 
 	fw, err := w.CreateFormFile("file", "file")
 	if err != nil {
-		return fmt.Errorf("could not create form file: %v", err)
+		return fmt.Errorf("could not create form file: %w", err)
 	}
 
 	if _, err := io.Copy(fw, license); err != nil {
-		return fmt.Errorf("could not create form file: %v", err)
+		return fmt.Errorf("could not create form file: %w", err)
 	}
 
 	if err := w.Close(); err != nil {
-		return fmt.Errorf("could not create form file: %v", err)
+		return fmt.Errorf("could not create form file: %w", err)
 	}
 
-	piq := FromPublic(iq)
-	req, err := piq.NewRequest("POST", restLicense, &b)
+	piq := fromPublicContext(ctx, iq)
+	endpoint, err := piq.endpoint("InstallLicense")
 	if err != nil {
-		return fmt.Errorf("could not create license request: %v", err)
+		return err
+	}
+
+	req, err := piq.NewRequest("POST", endpoint, &b)
+	if err != nil {
+		return fmt.Errorf("could not create license request: %w", err)
 	}
 	req.Header.Set("Content-Type", w.FormDataContentType())
 
-	if _, resp, err := piq.Do(req); err != nil && resp.StatusCode != http.StatusNoContent {
-		return fmt.Errorf("could not send license request: %v", err)
+	if _, _, err := piq.Do(req); err != nil {
+		return fmt.Errorf("could not send license request: %w", err)
 	}
 
 	return nil
 }
 
 func LicenseInfo(iq publiciq.IQ) (license NexusLicense, err error) {
-	body, _, err := FromPublic(iq).Get(restLicense)
+	return LicenseInfoContext(context.Background(), iq)
+}
+
+func LicenseInfoContext(ctx context.Context, iq publiciq.IQ) (license NexusLicense, err error) {
+	piq := fromPublicContext(ctx, iq)
+
+	endpoint, err := piq.endpoint("LicenseInfo")
+	if err != nil {
+		return license, err
+	}
+
+	body, _, err := piq.Get(endpoint)
+	if err != nil {
+		return license, fmt.Errorf("could not retrieve license: %w", err)
+	}
+
 	if err = json.Unmarshal(body, &license); err != nil {
-		return
+		return license, fmt.Errorf("could not parse license: %w", err)
 	}
 	return
 }
@@ -2,16 +2,18 @@ package privateiq
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/hokiegeek/gonexus-private/iq/privateiqtest"
 	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
 )
 
 func TestLicenseInfo(t *testing.T) {
-	tiq, err := publiciq.New("http://localhost:8070", "admin", "admin123")
-	if err != nil {
-		panic(err)
-	}
+	server := privateiqtest.NewServer()
+	defer server.Close()
+	tiq := server.IQ()
+
 	type args struct {
 		iq publiciq.IQ
 	}
@@ -35,3 +37,21 @@ func TestLicenseInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestInstallLicense(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	if err := InstallLicense(server.IQ(), strings.NewReader("dummy license")); err != nil {
+		t.Fatal(err)
+	}
+
+	license, err := LicenseInfo(server.IQ())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := server.License().Fingerprint; license.Fingerprint != want || want == "dummy-fingerprint" {
+		t.Errorf("got fingerprint %q after installing the license, want %q", license.Fingerprint, want)
+	}
+}
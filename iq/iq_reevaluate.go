@@ -1,6 +1,7 @@
 package privateiq
 
 import (
+	"context"
 	"fmt"
 
 	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
@@ -10,43 +11,69 @@ const restReportReevaluate = "rest/report/%s/%s/reevaluatePolicy"
 
 // ReevaluateReportByID hits the re-eval button on the specified report
 func ReevaluateReportByID(iq publiciq.IQ, appID, ReportID string) error {
-	endpoint := fmt.Sprintf(restReportReevaluate, appID, ReportID)
-	_, _, err := FromPublic(iq).Post(endpoint, nil)
+	return ReevaluateReportByIDContext(context.Background(), iq, appID, ReportID)
+}
+
+// ReevaluateReportByIDContext hits the re-eval button on the specified report
+func ReevaluateReportByIDContext(ctx context.Context, iq publiciq.IQ, appID, ReportID string) error {
+	piq := fromPublicContext(ctx, iq)
+
+	endpoint, err := piq.endpoint("ReevaluateReportByID", appID, ReportID)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = piq.Post(endpoint, nil)
 	return err
 }
 
 // ReevaluateReportByApp hits the re-eval button
 func ReevaluateReportByApp(iq publiciq.IQ, appID, stage string) error {
-	info, err := publiciq.GetReportInfoByAppIDStage(iq, appID, stage)
+	return ReevaluateReportByAppContext(context.Background(), iq, appID, stage)
+}
+
+// ReevaluateReportByAppContext hits the re-eval button
+func ReevaluateReportByAppContext(ctx context.Context, iq publiciq.IQ, appID, stage string) error {
+	piq := fromPublicContext(ctx, iq)
+
+	info, err := publiciq.GetReportInfoByAppIDStage(piq, appID, stage)
 	if err != nil {
-		return fmt.Errorf("did not find report for '%s' at '%s' build stage: %v", appID, stage, err)
+		return fmt.Errorf("did not find report for '%s' at '%s' build stage: %w", appID, stage, err)
 	}
 
-	return ReevaluateReportByID(iq, appID, info.ReportID())
+	return ReevaluateReportByIDContext(ctx, piq, appID, info.ReportID())
 }
 
 // ReevaluateAllReports hits the re-eval button on AllTheThings!
 func ReevaluateAllReports(iq publiciq.IQ) error {
-	apps, err := publiciq.GetAllApplications(iq)
+	return ReevaluateAllReportsContext(context.Background(), iq)
+}
+
+// ReevaluateAllReportsContext hits the re-eval button on AllTheThings!
+func ReevaluateAllReportsContext(ctx context.Context, iq publiciq.IQ) error {
+	piq := fromPublicContext(ctx, iq)
+
+	apps, err := publiciq.GetAllApplications(piq)
 	if err != nil {
-		return fmt.Errorf("could not retrieve applications: %v", err)
+		return fmt.Errorf("could not retrieve applications: %w", err)
 	}
 
 	for _, app := range apps {
+		if ctx.Err() != nil {
+			return fmt.Errorf("reevaluation stopped: %w", ctx.Err())
+		}
+
+		infos, err := publiciq.GetReportInfosByAppID(piq, app.PublicID)
 		if err != nil {
-			continue
+			return fmt.Errorf("could not retrieve application: %w", err)
 		}
-		var infos []publiciq.ReportInfo
-		if infos, err = publiciq.GetReportInfosByAppID(iq, app.PublicID); err == nil {
-			for _, info := range infos {
-				endpoint := fmt.Sprintf(restReportReevaluate, app.PublicID, info.ReportID())
-				_, _, err = FromPublic(iq).Post(endpoint, nil)
+
+		for _, info := range infos {
+			if err = ReevaluateReportByIDContext(ctx, piq, app.PublicID, info.ReportID()); err != nil {
+				return fmt.Errorf("could not reevaluate report of application '%s': %w", app.PublicID, err)
 			}
 		}
 	}
-	if err != nil {
-		return fmt.Errorf("could not retrieve application: %v", err)
-	}
 
 	return nil
 }
@@ -0,0 +1,38 @@
+package privateiq
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/hokiegeek/gonexus-private/iq/privateiqtest"
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+func TestReevaluateAllReports(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	var want []string
+	for _, id := range []string{"app1", "app2"} {
+		server.AddApplication(id, id, publiciq.RootOrganization)
+		for _, stage := range []string{publiciq.StageBuild, publiciq.StageRelease} {
+			want = append(want, id+"/"+server.AddReport(id, stage))
+		}
+	}
+
+	if err := ReevaluateAllReports(server.IQ()); err != nil {
+		t.Fatal(err)
+	}
+
+	got := server.Reevaluations()
+	sort.Strings(got)
+	sort.Strings(want)
+	if len(got) != len(want) {
+		t.Fatalf("reevaluated %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("reevaluated %v, want %v", got, want)
+		}
+	}
+}
@@ -1,6 +1,13 @@
 package privateiq
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
 	"time"
 
 	"github.com/sonatype-nexus-community/gonexus/iq"
@@ -27,3 +34,81 @@ func ExampleDeleteOrganization() {
 		panic(err)
 	}
 }
+
+func TestSessionReuseAndRefresh(t *testing.T) {
+	var sessions, calls int32
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path[1:] {
+		case restSessionPrivate:
+			n := atomic.AddInt32(&sessions, 1)
+			http.SetCookie(w, &http.Cookie{Name: csrfCookie, Value: fmt.Sprintf("token%d", n)})
+		default:
+			n := atomic.AddInt32(&calls, 1)
+			// Simulate the session expiring on the third call
+			if n == 3 && r.Header.Get(csrfHeader) == "token1" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			fmt.Fprint(w, r.Header.Get(csrfHeader))
+		}
+	}))
+	defer mock.Close()
+
+	pub, err := nexusiq.New(mock.URL, "dummy_user", "dummy_pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	iq := FromPublic(pub)
+
+	want := []string{"token1", "token1", "token2", "token2"}
+	for i, w := range want {
+		body, _, err := iq.Post("rest/dummy", bytes.NewBufferString("payload"))
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+		if string(body) != w {
+			t.Errorf("request %d used token %q, want %q", i, body, w)
+		}
+	}
+
+	if sessions != 2 {
+		t.Errorf("established %d sessions, want 2", sessions)
+	}
+
+	if FromPublic(iq) != iq {
+		t.Error("wrapping a private instance again did not return the same instance")
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path[1:] == restSessionPrivate {
+			http.SetCookie(w, &http.Cookie{Name: csrfCookie, Value: "token"})
+			return
+		}
+		// Never answer until the client gives up
+		<-r.Context().Done()
+	}))
+	defer mock.Close()
+
+	pub, err := nexusiq.New(mock.URL, "dummy_user", "dummy_pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	iq := FromPublic(pub)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, _, err := iq.GetContext(ctx, "rest/dummy"); err == nil {
+		t.Error("expected the request to fail once the context was done")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("request was not cancelled promptly, took %s", elapsed)
+	}
+
+	if _, err := WaiversContext(ctx, iq); err == nil {
+		t.Error("expected Waivers to fail with a done context")
+	}
+}
@@ -0,0 +1,128 @@
+package privateiq
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter caps the number of requests sent to IQ at once, and per second.
+// A Limiter can be shared by several instances so that, together, they do not exceed what the operator allows.
+// A nil *Limiter does not limit anything
+type Limiter struct {
+	slots chan struct{}
+
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// DefaultLimiter is used by the instances which were not given a Limiter. It is nil, and so unlimited, by default
+var DefaultLimiter *Limiter
+
+// NewLimiter creates a Limiter allowing up to the given number of concurrent requests, and of requests per second
+// with bursts of the given size. Zero, or less, disables the corresponding limit
+func NewLimiter(concurrency int, requestsPerSecond float64, burst int) *Limiter {
+	l := new(Limiter)
+	if concurrency > 0 {
+		l.slots = make(chan struct{}, concurrency)
+	}
+	if requestsPerSecond > 0 {
+		if burst < 1 {
+			burst = 1
+		}
+		l.rate = requestsPerSecond
+		l.burst = float64(burst)
+		l.tokens = l.burst
+	}
+	return l
+}
+
+// WithLimiter sets the Limiter which every request of the instance goes through, including retries
+func WithLimiter(l *Limiter) Option {
+	return func(iq *privateiq) {
+		iq.limiter = l
+	}
+}
+
+// WithConcurrency limits the instance to the given number of concurrent requests
+func WithConcurrency(n int) Option {
+	return WithLimiter(NewLimiter(n, 0, 0))
+}
+
+// WithRateLimit limits the instance to the given number of requests per second, with bursts of the given size
+func WithRateLimit(requestsPerSecond float64, burst int) Option {
+	return WithLimiter(NewLimiter(0, requestsPerSecond, burst))
+}
+
+// acquire waits until a request may be sent, returning the function which signals that it completed
+func (l *Limiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	release := func() {}
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+			release = func() { <-l.slots }
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if wait := l.reserve(); wait > 0 {
+		if err := sleep(ctx, wait); err != nil {
+			l.cancel()
+			release()
+			return nil, err
+		}
+	}
+
+	return release, nil
+}
+
+// reserve takes a token from the bucket, returning how long to wait until it is available
+func (l *Limiter) reserve() time.Duration {
+	if l.rate == 0 {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// cancel returns the token of a request which was abandoned while waiting for it
+func (l *Limiter) cancel() {
+	if l.rate == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens++
+}
+
+// workers returns how many goroutines a fan-out should use, which is no more than the requests allowed at once
+func (l *Limiter) workers(max int) int {
+	if l != nil && l.slots != nil && cap(l.slots) < max {
+		return cap(l.slots)
+	}
+	return max
+}
@@ -0,0 +1,116 @@
+package privateiq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hokiegeek/gonexus-private/iq/privateiqtest"
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+// inFlight is a transport which tracks the highest number of concurrent requests
+type inFlight struct {
+	current, max int32
+}
+
+func (f *inFlight) RoundTrip(req *http.Request) (*http.Response, error) {
+	n := atomic.AddInt32(&f.current, 1)
+	defer atomic.AddInt32(&f.current, -1)
+	for {
+		max := atomic.LoadInt32(&f.max)
+		if n <= max || atomic.CompareAndSwapInt32(&f.max, max, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	server := waiversTestServer()
+	defer server.Close()
+	for i := 0; i < 10; i++ {
+		id := fmt.Sprintf("extra%d", i)
+		server.AddApplication(id, id, publiciq.RootOrganization)
+		server.AddReport(id, publiciq.StageBuild, publiciq.Component{Hash: id + "hash"})
+	}
+
+	transport := new(inFlight)
+	limiter := NewLimiter(3, 0, 0)
+	iq := FromPublic(server.IQ(), WithTransport(transport), WithLimiter(limiter))
+
+	// Another instance sharing the limiter cooperates with the first one
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if _, err := Waivers(FromPublic(server.IQ(), WithTransport(transport), WithLimiter(limiter))); err != nil {
+			t.Error(err)
+		}
+	}()
+	if _, err := Waivers(iq); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	if transport.max > 3 {
+		t.Errorf("%d requests were sent at once, want at most 3", transport.max)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	limiter := NewLimiter(0, 100, 2)
+
+	start := time.Now()
+	for i := 0; i < 7; i++ {
+		release, err := limiter.acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+
+	// The burst of 2 goes through at once, the 5 other requests are spaced by 10ms
+	if elapsed := time.Since(start); elapsed < 45*time.Millisecond {
+		t.Errorf("7 requests took %s, want at least 50ms", elapsed)
+	}
+}
+
+func TestLimiterContext(t *testing.T) {
+	limiter := NewLimiter(1, 0, 0)
+
+	release, err := limiter.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := LicenseInfoContext(ctx, FromPublic(server.IQ(), WithLimiter(limiter))); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the request waiting for the limiter to time out, got %v", err)
+	}
+
+	release()
+}
+
+func TestNilLimiter(t *testing.T) {
+	var limiter *Limiter
+	release, err := limiter.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+
+	if n := limiter.workers(20); n != 20 {
+		t.Errorf("workers() = %d, want 20", n)
+	}
+}
@@ -0,0 +1,342 @@
+package privateiq
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+// LockfileFormat is the format of a file listing the resolved dependencies of a project
+type LockfileFormat string
+
+// The lockfile formats understood by ParseLockfile
+const (
+	GoSum       LockfileFormat = "go.sum"
+	PackageLock LockfileFormat = "package-lock.json"
+	YarnLock    LockfileFormat = "yarn.lock"
+	// MavenDependencies is the output of mvn dependency:list or dependency:tree
+	MavenDependencies LockfileFormat = "maven"
+)
+
+// ErrUnknownLockfileFormat is returned when the format of a lockfile cannot be told from its name
+var ErrUnknownLockfileFormat = errors.New("unknown lockfile format")
+
+// LockfileFormatOf returns the format of the lockfile with the given name. Maven dependency lists have no set name
+func LockfileFormatOf(filename string) (LockfileFormat, error) {
+	switch path.Base(filename) {
+	case "go.sum":
+		return GoSum, nil
+	case "package-lock.json", "npm-shrinkwrap.json":
+		return PackageLock, nil
+	case "yarn.lock":
+		return YarnLock, nil
+	}
+	return "", fmt.Errorf("%s: %w", filename, ErrUnknownLockfileFormat)
+}
+
+// LockfileEntry is a dependency listed by a lockfile
+type LockfileEntry struct {
+	// Line is the line of the lockfile, counted from 1, which lists the dependency
+	Line int
+	// Text is what the lockfile lists the dependency as, such as its line, or its key in package-lock.json
+	Text      string
+	Component publiciq.Component
+}
+
+// ParseLockfile lists the dependencies of a lockfile. Maven dependencies are identified by their coordinates,
+// and the others by their package URL. The lines of go.sum which only list the go.mod file of a module are skipped
+func ParseLockfile(format LockfileFormat, lockfile io.Reader) ([]LockfileEntry, error) {
+	buf, err := ioutil.ReadAll(lockfile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read lockfile: %w", err)
+	}
+
+	switch format {
+	case GoSum:
+		return parseGoSum(buf)
+	case PackageLock:
+		return parsePackageLock(buf)
+	case YarnLock:
+		return parseYarnLock(buf)
+	case MavenDependencies:
+		return parseMavenDependencies(buf)
+	}
+
+	return nil, fmt.Errorf("%s: %w", format, ErrUnknownLockfileFormat)
+}
+
+// eachLine calls the function with every line and its number, counted from 1
+func eachLine(buf []byte, f func(n int, line string)) error {
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		f(n, scanner.Text())
+	}
+	return scanner.Err()
+}
+
+func goComponent(module, version string) publiciq.Component {
+	return publiciq.Component{PackageURL: fmt.Sprintf("pkg:golang/%s@%s", module, strings.Replace(version, "+", "%2B", -1))}
+}
+
+func npmComponent(name, version string) publiciq.Component {
+	return publiciq.Component{PackageURL: fmt.Sprintf("pkg:npm/%s@%s", strings.Replace(name, "@", "%40", 1), version)}
+}
+
+func parseGoSum(buf []byte) ([]LockfileEntry, error) {
+	entries := make([]LockfileEntry, 0)
+	err := eachLine(buf, func(n int, line string) {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || strings.HasSuffix(fields[1], "/go.mod") {
+			return
+		}
+		entries = append(entries, LockfileEntry{Line: n, Text: line, Component: goComponent(fields[0], fields[1])})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not parse go.sum: %w", err)
+	}
+	return entries, nil
+}
+
+// packageLockPackage is a package of lockfile versions 2 and 3
+type packageLockPackage struct {
+	Version string `json:"version"`
+	Link    bool   `json:"link"`
+}
+
+// packageLockDependency is a dependency of lockfile version 1
+type packageLockDependency struct {
+	Version      string                           `json:"version"`
+	Dependencies map[string]packageLockDependency `json:"dependencies"`
+}
+
+// parsePackageLock reads the packages of lockfile versions 2 and 3, or the dependencies of version 1.
+// npm sorts the keys of package-lock.json, so walking them sorted follows the order of the file
+func parsePackageLock(buf []byte) ([]LockfileEntry, error) {
+	var doc struct {
+		Packages     map[string]packageLockPackage `json:"packages"`
+		Dependencies json.RawMessage               `json:"dependencies"`
+	}
+	if err := json.Unmarshal(buf, &doc); err != nil {
+		return nil, fmt.Errorf("could not parse package-lock.json: %w", err)
+	}
+
+	type dependency struct {
+		key, name, version string
+	}
+	deps := make([]dependency, 0)
+
+	if doc.Packages != nil {
+		keys := make([]string, 0, len(doc.Packages))
+		for k := range doc.Packages {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			p := doc.Packages[key]
+			i := strings.LastIndex(key, "node_modules/")
+			if i < 0 || p.Link || p.Version == "" {
+				continue
+			}
+			deps = append(deps, dependency{key, key[i+len("node_modules/"):], p.Version})
+		}
+	} else {
+		var walk func(map[string]packageLockDependency)
+		walk = func(d map[string]packageLockDependency) {
+			names := make([]string, 0, len(d))
+			for n := range d {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				deps = append(deps, dependency{name, name, d[name].Version})
+				walk(d[name].Dependencies)
+			}
+		}
+		var v1 map[string]packageLockDependency
+		if err := json.Unmarshal(doc.Dependencies, &v1); err != nil && len(doc.Dependencies) > 0 {
+			return nil, fmt.Errorf("could not parse package-lock.json: %w", err)
+		}
+		walk(v1)
+	}
+
+	text := string(buf)
+	offset := strings.Index(text, `"packages"`)
+	if doc.Packages == nil {
+		offset = strings.Index(text, `"dependencies"`)
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	entries := make([]LockfileEntry, 0, len(deps))
+	for _, d := range deps {
+		e := LockfileEntry{Text: d.key, Component: npmComponent(d.name, d.version)}
+		if i := strings.Index(text[offset:], fmt.Sprintf("%q:", d.key)); i >= 0 {
+			offset += i
+			e.Line = strings.Count(text[:offset], "\n") + 1
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// parseYarnLock reads the lockfiles of yarn 1, and of later versions which are YAML
+func parseYarnLock(buf []byte) ([]LockfileEntry, error) {
+	entries := make([]LockfileEntry, 0)
+	var header string
+	var headerLine int
+
+	err := eachLine(buf, func(n int, line string) {
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			return
+		case !strings.HasPrefix(line, " "):
+			header, headerLine = strings.TrimSuffix(line, ":"), n
+			return
+		}
+
+		field := strings.Fields(strings.TrimSpace(line))
+		if header == "" || len(field) != 2 || strings.TrimSuffix(field[0], ":") != "version" {
+			return
+		}
+
+		// The header lists every range resolved to the version, such as "lodash@^4.17.0", lodash@^4.17.20
+		spec := strings.Trim(strings.TrimSpace(strings.Split(header, ",")[0]), `"`)
+		at := strings.LastIndex(spec, "@")
+		if at <= 0 || strings.Contains(spec, "@workspace:") || strings.Contains(spec, "@patch:") {
+			return
+		}
+
+		version := strings.Trim(field[1], `"`)
+		entries = append(entries, LockfileEntry{Line: headerLine, Text: header, Component: npmComponent(spec[:at], version)})
+		header = ""
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not parse yarn.lock: %w", err)
+	}
+	return entries, nil
+}
+
+// Matches a dependency as listed by mvn dependency:list or dependency:tree, such as
+// com.google.guava:guava:jar:30.0-jre:compile, or with a classifier before the version
+var mavenDependency = regexp.MustCompile(`([\w.\-]+):([\w.\-]+):([\w.\-]+):(?:([\w.\-]+):)?([\w.\-]+):(compile|provided|runtime|test|system|import)\b`)
+
+func parseMavenDependencies(buf []byte) ([]LockfileEntry, error) {
+	entries := make([]LockfileEntry, 0)
+	err := eachLine(buf, func(n int, line string) {
+		m := mavenDependency.FindStringSubmatch(line)
+		if m == nil {
+			return
+		}
+
+		coords := publiciq.Coordinates{GroupID: m[1], ArtifactID: m[2], Extension: m[3], Classifier: m[4], Version: m[5]}
+		entries = append(entries, LockfileEntry{
+			Line:      n,
+			Text:      strings.TrimSpace(line),
+			Component: publiciq.Component{ComponentID: &publiciq.ComponentIdentifier{Format: "maven", Coordinates: coords}},
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not parse Maven dependencies: %w", err)
+	}
+	return entries, nil
+}
+
+// LockfileResult is the evaluation of a dependency listed by a lockfile
+type LockfileResult struct {
+	Entry  LockfileEntry
+	Result publiciq.ComponentEvaluationResult
+}
+
+// LockfileEvaluation is the evaluation of the dependencies of a lockfile
+type LockfileEvaluation struct {
+	Evaluation *publiciq.Evaluation
+	// Results are in the order of the lockfile. A dependency listed more than once is only evaluated once
+	Results []LockfileResult
+	// Unevaluated lists, in the order of the lockfile, the dependencies IQ returned no result for
+	Unevaluated []LockfileEntry
+}
+
+// EvaluateLockfile evaluates the dependencies listed by a lockfile using Root Organization only. See ParseLockfile
+func EvaluateLockfile(iq publiciq.IQ, format LockfileFormat, lockfile io.Reader) (*LockfileEvaluation, error) {
+	return EvaluateLockfileWithOrgContext(context.Background(), iq, publiciq.RootOrganization, format, lockfile)
+}
+
+// EvaluateLockfileContext evaluates the dependencies listed by a lockfile using Root Organization only. See ParseLockfile
+func EvaluateLockfileContext(ctx context.Context, iq publiciq.IQ, format LockfileFormat, lockfile io.Reader) (*LockfileEvaluation, error) {
+	return EvaluateLockfileWithOrgContext(ctx, iq, publiciq.RootOrganization, format, lockfile)
+}
+
+// EvaluateLockfileWithOrg evaluates the dependencies listed by a lockfile using the policies of the organization
+// with the given ID or name. See ParseLockfile
+func EvaluateLockfileWithOrg(iq publiciq.IQ, organization string, format LockfileFormat, lockfile io.Reader) (*LockfileEvaluation, error) {
+	return EvaluateLockfileWithOrgContext(context.Background(), iq, organization, format, lockfile)
+}
+
+// EvaluateLockfileWithOrgContext evaluates the dependencies listed by a lockfile using the policies of the organization
+// with the given ID or name. See ParseLockfile
+func EvaluateLockfileWithOrgContext(ctx context.Context, iq publiciq.IQ, organization string, format LockfileFormat, lockfile io.Reader) (*LockfileEvaluation, error) {
+	entries, err := ParseLockfile(format, lockfile)
+	if err != nil {
+		return nil, err
+	}
+
+	components := make([]publiciq.Component, 0, len(entries))
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		key := lockfileKey(e.Component)
+		if !seen[key] {
+			seen[key] = true
+			components = append(components, e.Component)
+		}
+	}
+
+	eval := &LockfileEvaluation{Results: make([]LockfileResult, 0, len(entries)), Unevaluated: make([]LockfileEntry, 0)}
+	if len(components) == 0 {
+		eval.Evaluation = &publiciq.Evaluation{Results: make([]publiciq.ComponentEvaluationResult, 0)}
+		return eval, nil
+	}
+
+	if eval.Evaluation, err = EvaluateComponentsWithOrgContext(ctx, iq, organization, components); err != nil {
+		return eval, err
+	}
+
+	// Results are matched by component rather than by position, as IQ does not promise to keep the order
+	results := make(map[string]publiciq.ComponentEvaluationResult, len(eval.Evaluation.Results))
+	for _, r := range eval.Evaluation.Results {
+		if r.Component.PackageURL != "" {
+			results[r.Component.PackageURL] = r
+		}
+		if r.Component.ComponentID != nil {
+			results[r.Component.ComponentID.String()] = r
+		}
+	}
+	for _, e := range entries {
+		if r, ok := results[lockfileKey(e.Component)]; ok {
+			eval.Results = append(eval.Results, LockfileResult{Entry: e, Result: r})
+		} else {
+			eval.Unevaluated = append(eval.Unevaluated, e)
+		}
+	}
+
+	return eval, nil
+}
+
+func lockfileKey(c publiciq.Component) string {
+	if c.ComponentID != nil {
+		return c.ComponentID.String()
+	}
+	return c.PackageURL
+}
@@ -0,0 +1,209 @@
+package privateiq
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hokiegeek/gonexus-private/iq/privateiqtest"
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+var lockfiles = map[LockfileFormat]string{
+	GoSum: `github.com/pkg/errors v0.9.1 h1:FEBLx1zS214owpjy7qsBeixbURkuhQAwrK5UwLGTwt4=
+github.com/pkg/errors v0.9.1/go.mod h1:bwawxfHBFNV+L2hUp1rHADufV3IMtnDRdf1r5NINEl0=
+golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9/go.mod h1:LzIPMQfyMNhhGPhUkYOs5KpL4U8rLKemX1yGLhDgUto=
+github.com/Masterminds/goutils v1.1.0+incompatible h1:something=
+`,
+	PackageLock: `{
+  "name": "app",
+  "lockfileVersion": 2,
+  "packages": {
+    "": {
+      "name": "app",
+      "dependencies": {
+        "@babel/core": "^7.12.0"
+      }
+    },
+    "node_modules/@babel/core": {
+      "version": "7.12.3"
+    },
+    "node_modules/@babel/core/node_modules/semver": {
+      "version": "5.7.1"
+    },
+    "node_modules/semver": {
+      "version": "7.3.2"
+    }
+  },
+  "dependencies": {
+    "@babel/core": {
+      "version": "7.12.3"
+    }
+  }
+}
+`,
+	YarnLock: `# THIS IS AN AUTOGENERATED FILE. DO NOT EDIT THIS FILE DIRECTLY.
+# yarn lockfile v1
+
+
+"@babel/core@^7.12.0":
+  version "7.12.3"
+  resolved "https://registry.yarnpkg.com/@babel/core/-/core-7.12.3.tgz"
+
+semver@^5.4.1, semver@^5.7.0:
+  version "5.7.1"
+
+semver@^7.3.2:
+  version "7.3.2"
+`,
+	MavenDependencies: `[INFO] --- maven-dependency-plugin:3.1.2:list (default-cli) @ app ---
+[INFO]
+[INFO] The following files have been resolved:
+[INFO]    com.google.guava:guava:jar:30.0-jre:compile
+[INFO]    io.netty:netty-transport-native-epoll:jar:linux-x86_64:4.1.50.Final:runtime
+[INFO]    junit:junit:jar:4.13:test (optional)
+`,
+}
+
+func TestParseLockfile(t *testing.T) {
+	maven := func(g, a, classifier, v string) publiciq.Component {
+		return publiciq.Component{ComponentID: &publiciq.ComponentIdentifier{
+			Format:      "maven",
+			Coordinates: publiciq.Coordinates{GroupID: g, ArtifactID: a, Extension: "jar", Classifier: classifier, Version: v},
+		}}
+	}
+
+	for format, want := range map[LockfileFormat][]struct {
+		line int
+		id   string
+	}{
+		GoSum: {
+			{1, "pkg:golang/github.com/pkg/errors@v0.9.1"},
+			{4, "pkg:golang/github.com/Masterminds/goutils@v1.1.0%2Bincompatible"},
+		},
+		PackageLock: {
+			{11, "pkg:npm/%40babel/core@7.12.3"},
+			{14, "pkg:npm/semver@5.7.1"},
+			{17, "pkg:npm/semver@7.3.2"},
+		},
+		YarnLock: {
+			{5, "pkg:npm/%40babel/core@7.12.3"},
+			{9, "pkg:npm/semver@5.7.1"},
+			{12, "pkg:npm/semver@7.3.2"},
+		},
+		MavenDependencies: {
+			{4, maven("com.google.guava", "guava", "", "30.0-jre").ComponentID.String()},
+			{5, maven("io.netty", "netty-transport-native-epoll", "linux-x86_64", "4.1.50.Final").ComponentID.String()},
+			{6, maven("junit", "junit", "", "4.13").ComponentID.String()},
+		},
+	} {
+		entries, err := ParseLockfile(format, strings.NewReader(lockfiles[format]))
+		if err != nil {
+			t.Errorf("%s: %v", format, err)
+			continue
+		}
+		if len(entries) != len(want) {
+			t.Errorf("%s: got entries %v", format, entries)
+			continue
+		}
+		for i, e := range entries {
+			if e.Line != want[i].line || lockfileKey(e.Component) != want[i].id {
+				t.Errorf("%s: got %s on line %d, want %s on line %d", format, lockfileKey(e.Component), e.Line, want[i].id, want[i].line)
+			}
+		}
+	}
+}
+
+func TestEvaluateLockfile(t *testing.T) {
+	evaluationPollInterval = time.Millisecond
+
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	server.AddKnownComponent(privateiqtest.KnownComponent{Component: publiciq.Component{PackageURL: "pkg:npm/semver@5.7.1"}})
+
+	lockfile := lockfiles[YarnLock] + "\nsemver@~5.7.0:\n  version \"5.7.1\"\n"
+	eval, err := EvaluateLockfile(server.IQ(), YarnLock, strings.NewReader(lockfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(eval.Evaluation.Results) != 3 || len(eval.Results) != 4 {
+		t.Fatalf("evaluated %d components for %d entries", len(eval.Evaluation.Results), len(eval.Results))
+	}
+	for _, r := range eval.Results {
+		exact := r.Result.MatchState == "exact"
+		if want := strings.HasPrefix(r.Entry.Text, "semver@^5") || strings.HasPrefix(r.Entry.Text, "semver@~5"); exact != want {
+			t.Errorf("line %d %q matched %q", r.Entry.Line, r.Entry.Text, r.Result.MatchState)
+		}
+	}
+}
+
+// shuffling is a transport which reverses the results of evaluations and drops what was the last one,
+// as nothing promises IQ keeps their order or answers for every component
+type shuffling struct{}
+
+func (shuffling) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK || !strings.Contains(req.URL.Path, "/results/") {
+		return resp, err
+	}
+	defer resp.Body.Close()
+
+	var eval map[string]json.RawMessage
+	var results []json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&eval); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(eval["results"], &results); err != nil {
+		return nil, err
+	}
+
+	shuffled := make([]json.RawMessage, 0, len(results))
+	for i := len(results) - 2; i >= 0; i-- {
+		shuffled = append(shuffled, results[i])
+	}
+	eval["results"], _ = json.Marshal(shuffled)
+	buf, _ := json.Marshal(eval)
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(buf))
+	resp.ContentLength = int64(len(buf))
+	resp.Header.Del("Content-Length")
+	return resp, nil
+}
+
+func TestEvaluateLockfileMatchesResults(t *testing.T) {
+	evaluationPollInterval = time.Millisecond
+
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	server.AddKnownComponent(privateiqtest.KnownComponent{Component: publiciq.Component{PackageURL: "pkg:golang/github.com/pkg/errors@v0.9.1"}})
+
+	iq := FromPublic(server.IQ(), WithTransport(shuffling{}))
+	eval, err := EvaluateLockfile(iq, GoSum, strings.NewReader(lockfiles[GoSum]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(eval.Results) != 1 || len(eval.Unevaluated) != 1 {
+		t.Fatalf("got %d results and %d unevaluated entries", len(eval.Results), len(eval.Unevaluated))
+	}
+	for _, r := range eval.Results {
+		if r.Result.Component.PackageURL != r.Entry.Component.PackageURL {
+			t.Errorf("line %d %q got the result of %s", r.Entry.Line, r.Entry.Text, r.Result.Component.PackageURL)
+		}
+		if exact := r.Result.MatchState == "exact"; exact != strings.HasPrefix(r.Entry.Text, "github.com/pkg/errors") {
+			t.Errorf("line %d %q matched %q", r.Entry.Line, r.Entry.Text, r.Result.MatchState)
+		}
+	}
+	for _, e := range eval.Unevaluated {
+		if e.Component.PackageURL != "pkg:golang/github.com/Masterminds/goutils@v1.1.0%2Bincompatible" {
+			t.Errorf("line %d %q was not evaluated", e.Line, e.Text)
+		}
+	}
+}
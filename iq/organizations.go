@@ -0,0 +1,203 @@
+package privateiq
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+const (
+	restOrganizationsPrivate = "rest/organization"
+	restOrganizationMove     = "rest/organization/%s/move/destination/%s"
+	restApplicationMove      = "rest/application/%s/move/organization/%s"
+)
+
+type organizationJSON struct {
+	ID                   string `json:"id"`
+	Name                 string `json:"name"`
+	ParentOrganizationID string `json:"parentOrganizationId,omitempty"`
+}
+
+// OrganizationNode is an organization within the organization tree, along with its applications
+type OrganizationNode struct {
+	ID           string
+	Name         string
+	Parent       *OrganizationNode
+	Children     []*OrganizationNode
+	Applications []publiciq.Application
+}
+
+// Path returns the names of the organizations from the Root Organization down to this one
+func (n *OrganizationNode) Path() []string {
+	if n.Parent == nil {
+		return []string{n.Name}
+	}
+	return append(n.Parent.Path(), n.Name)
+}
+
+// Walk calls the function on the organization and then on its descendants, depth first.
+// It stops at, and returns, the first error
+func (n *OrganizationNode) Walk(fn func(*OrganizationNode) error) error {
+	if err := fn(n); err != nil {
+		return err
+	}
+	for _, c := range n.Children {
+		if err := c.Walk(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OrganizationTree is the hierarchy of the organizations of IQ
+type OrganizationTree struct {
+	Root *OrganizationNode
+	byID map[string]*OrganizationNode
+}
+
+// Find returns the organization with the given id
+func (t *OrganizationTree) Find(organizationID string) (*OrganizationNode, bool) {
+	n, ok := t.byID[organizationID]
+	return n, ok
+}
+
+// RenameOrganization renames the organization with the given id
+func RenameOrganization(iq publiciq.IQ, organizationID, name string) error {
+	return RenameOrganizationContext(context.Background(), iq, organizationID, name)
+}
+
+// RenameOrganizationContext renames the organization with the given id
+func RenameOrganizationContext(ctx context.Context, iq publiciq.IQ, organizationID, name string) error {
+	piq := fromPublicContext(ctx, iq)
+
+	endpoint, err := piq.endpoint("RenameOrganization", organizationID)
+	if err != nil {
+		return err
+	}
+
+	request, err := json.Marshal(organizationJSON{ID: organizationID, Name: name})
+	if err != nil {
+		return err
+	}
+
+	if _, _, err := piq.Put(endpoint, bytes.NewBuffer(request)); err != nil {
+		return fmt.Errorf("organization '%s' not renamed: %w", organizationID, err)
+	}
+
+	return nil
+}
+
+// MoveOrganization moves the organization with the given id, along with its descendants, under a new parent
+func MoveOrganization(iq publiciq.IQ, organizationID, parentID string) error {
+	return MoveOrganizationContext(context.Background(), iq, organizationID, parentID)
+}
+
+// MoveOrganizationContext moves the organization with the given id, along with its descendants, under a new parent
+func MoveOrganizationContext(ctx context.Context, iq publiciq.IQ, organizationID, parentID string) error {
+	piq := fromPublicContext(ctx, iq)
+
+	endpoint, err := piq.endpoint("MoveOrganization", organizationID, parentID)
+	if err != nil {
+		return err
+	}
+
+	if _, _, err := piq.Post(endpoint, nil); err != nil {
+		return fmt.Errorf("organization '%s' not moved to '%s': %w", organizationID, parentID, err)
+	}
+
+	return nil
+}
+
+// MoveApplication moves the application with the given public id to another organization
+func MoveApplication(iq publiciq.IQ, appPublicID, organizationID string) error {
+	return MoveApplicationContext(context.Background(), iq, appPublicID, organizationID)
+}
+
+// MoveApplicationContext moves the application with the given public id to another organization
+func MoveApplicationContext(ctx context.Context, iq publiciq.IQ, appPublicID, organizationID string) error {
+	piq := fromPublicContext(ctx, iq)
+
+	app, err := getApplicationByPublicID(piq, appPublicID)
+	if err != nil {
+		return err
+	}
+
+	endpoint, err := piq.endpoint("MoveApplication", app.ID, organizationID)
+	if err != nil {
+		return err
+	}
+
+	if _, _, err := piq.Post(endpoint, nil); err != nil {
+		return fmt.Errorf("application '%s' not moved to '%s': %w", appPublicID, organizationID, err)
+	}
+
+	return nil
+}
+
+// GetOrganizationTree returns the hierarchy of the organizations, and their applications, starting at the Root Organization
+func GetOrganizationTree(iq publiciq.IQ) (*OrganizationTree, error) {
+	return GetOrganizationTreeContext(context.Background(), iq)
+}
+
+// GetOrganizationTreeContext returns the hierarchy of the organizations, and their applications, starting at the Root Organization
+func GetOrganizationTreeContext(ctx context.Context, iq publiciq.IQ) (*OrganizationTree, error) {
+	piq := fromPublicContext(ctx, iq)
+
+	endpoint, err := piq.endpoint("GetOrganizationTree")
+	if err != nil {
+		return nil, err
+	}
+
+	body, _, err := piq.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve organizations: %w", err)
+	}
+
+	var orgs []organizationJSON
+	if err = json.Unmarshal(body, &orgs); err != nil {
+		return nil, fmt.Errorf("could not parse organizations: %w", err)
+	}
+
+	apps, err := getAllApplications(piq)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildOrganizationTree(orgs, apps)
+}
+
+func buildOrganizationTree(orgs []organizationJSON, apps []publiciq.Application) (*OrganizationTree, error) {
+	tree := &OrganizationTree{byID: make(map[string]*OrganizationNode, len(orgs))}
+	for _, o := range orgs {
+		tree.byID[o.ID] = &OrganizationNode{ID: o.ID, Name: o.Name}
+	}
+
+	for _, o := range orgs {
+		node := tree.byID[o.ID]
+		if o.ID == publiciq.RootOrganization {
+			tree.Root = node
+			continue
+		}
+
+		parent, ok := tree.byID[o.ParentOrganizationID]
+		if !ok {
+			return nil, fmt.Errorf("parent '%s' of organization '%s': %w", o.ParentOrganizationID, o.ID, ErrNotFound)
+		}
+		node.Parent = parent
+		parent.Children = append(parent.Children, node)
+	}
+	if tree.Root == nil {
+		return nil, fmt.Errorf("root organization: %w", ErrNotFound)
+	}
+
+	for _, a := range apps {
+		if org, ok := tree.byID[a.OrganizationID]; ok {
+			org.Applications = append(org.Applications, a)
+		}
+	}
+
+	return tree, nil
+}
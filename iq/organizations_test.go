@@ -0,0 +1,120 @@
+package privateiq
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hokiegeek/gonexus-private/iq/privateiqtest"
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+func TestRenameOrganization(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+	org := server.AddOrganization("foo", "")
+	server.AddOrganization("taken", "")
+
+	if err := RenameOrganization(server.IQ(), org.ID, "bar"); err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range server.Organizations() {
+		if o.ID == org.ID && o.Name != "bar" {
+			t.Errorf("organization named %q, want bar", o.Name)
+		}
+	}
+
+	var apiErr *APIError
+	if err := RenameOrganization(server.IQ(), org.ID, "taken"); !errors.As(err, &apiErr) || apiErr.StatusCode != 400 {
+		t.Errorf("expected a duplicate name to be rejected, got %v", err)
+	}
+	if err := RenameOrganization(server.IQ(), "missing", "baz"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestMoveOrganization(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+	parent := server.AddOrganization("parent", "")
+	child := server.AddOrganization("child", parent.ID)
+	dest := server.AddOrganization("dest", "")
+
+	if err := MoveOrganization(server.IQ(), parent.ID, dest.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tree, err := GetOrganizationTree(server.IQ())
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, ok := tree.Find(child.ID)
+	if !ok {
+		t.Fatal("moved organization's child not found in the tree")
+	}
+	if want := []string{"Root Organization", "dest", "parent", "child"}; !reflect.DeepEqual(node.Path(), want) {
+		t.Errorf("child is at %v, want %v", node.Path(), want)
+	}
+
+	var apiErr *APIError
+	if err := MoveOrganization(server.IQ(), dest.ID, child.ID); !errors.As(err, &apiErr) || apiErr.StatusCode != 400 {
+		t.Errorf("expected moving an organization under its descendant to be rejected, got %v", err)
+	}
+	if err := MoveOrganization(server.IQ(), parent.ID, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestMoveApplication(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+	org := server.AddOrganization("foo", "")
+	server.AddApplication("app", "app", publiciq.RootOrganization)
+
+	if err := MoveApplication(server.IQ(), "app", org.ID); err != nil {
+		t.Fatal(err)
+	}
+	if apps := server.Applications(); apps[0].OrganizationID != org.ID {
+		t.Errorf("application is in organization %s, want %s", apps[0].OrganizationID, org.ID)
+	}
+
+	if err := MoveApplication(server.IQ(), "missing", org.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing application, got %v", err)
+	}
+	if err := MoveApplication(server.IQ(), "app", "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing organization, got %v", err)
+	}
+}
+
+func TestGetOrganizationTree(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+	a := server.AddOrganization("a", "")
+	server.AddOrganization("b", "")
+	aa := server.AddOrganization("aa", a.ID)
+	server.AddApplication("app1", "app1", aa.ID)
+	server.AddApplication("app2", "app2", aa.ID)
+
+	tree, err := GetOrganizationTree(server.IQ())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tree.Root.ID != publiciq.RootOrganization || len(tree.Root.Children) != 2 {
+		t.Fatalf("unexpected root: %+v", tree.Root)
+	}
+
+	var visited []string
+	tree.Root.Walk(func(n *OrganizationNode) error {
+		visited = append(visited, n.Name)
+		return nil
+	})
+	if want := []string{"Root Organization", "a", "aa", "b"}; !reflect.DeepEqual(visited, want) {
+		t.Errorf("walked %v, want %v", visited, want)
+	}
+
+	node, _ := tree.Find(aa.ID)
+	if node.Parent.ID != a.ID || len(node.Applications) != 2 {
+		t.Errorf("unexpected organization: %+v", node)
+	}
+}
@@ -2,8 +2,8 @@ package privateiq
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"mime/multipart"
 
@@ -87,9 +87,19 @@ type IQPolicySet struct {
 
 // ExportPolicies returns the policies of the indicated IQ server
 func ExportPolicies(iq publiciq.IQ) (p IQPolicySet, err error) {
-	endpoint := fmt.Sprintf(restPolicyExportPrivate, "ROOT_ORGANIZATION_ID")
+	return ExportPoliciesContext(context.Background(), iq)
+}
+
+// ExportPoliciesContext returns the policies of the indicated IQ server
+func ExportPoliciesContext(ctx context.Context, iq publiciq.IQ) (p IQPolicySet, err error) {
+	piq := fromPublicContext(ctx, iq)
+
+	endpoint, err := piq.endpoint("ExportPolicies", "ROOT_ORGANIZATION_ID")
+	if err != nil {
+		return
+	}
 
-	body, _, err := FromPublic(iq).Get(endpoint)
+	body, _, err := piq.Get(endpoint)
 	if err != nil {
 		return
 	}
@@ -101,9 +111,16 @@ func ExportPolicies(iq publiciq.IQ) (p IQPolicySet, err error) {
 
 // ImportPolicies imports the given policies
 func ImportPolicies(iq publiciq.IQ, file io.Reader) error {
+	return ImportPoliciesContext(context.Background(), iq, file)
+}
 
-	piq := FromPublic(iq)
+// ImportPoliciesContext imports the given policies
+func ImportPoliciesContext(ctx context.Context, iq publiciq.IQ, file io.Reader) error {
+	return importPolicies(fromPublicContext(ctx, iq), "ROOT_ORGANIZATION_ID", file)
+}
 
+// importPolicies imports the given policies into the organization
+func importPolicies(piq *privateiq, organizationID string, file io.Reader) error {
 	var b bytes.Buffer
 	w := multipart.NewWriter(&b)
 
@@ -112,18 +129,24 @@ func ImportPolicies(iq publiciq.IQ, file io.Reader) error {
 		return err
 	}
 
-	_, err = io.Copy(fw, file)
+	if _, err = io.Copy(fw, file); err != nil {
+		return err
+	}
 
 	if err := w.Close(); err != nil {
 		return err
 	}
 
-	endpoint := fmt.Sprintf(restPolicyImportPrivate, "ROOT_ORGANIZATION_ID")
+	endpoint, err := piq.endpoint("ImportPolicies", organizationID)
+	if err != nil {
+		return err
+	}
+
 	req, err := piq.NewRequest("POST", endpoint, &b)
-	req.Header.Set("Content-Type", w.FormDataContentType())
 	if err != nil {
 		return err
 	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
 
 	_, _, err = piq.Do(req)
 
@@ -0,0 +1,41 @@
+package privateiq
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/hokiegeek/gonexus-private/iq/privateiqtest"
+)
+
+func TestExportImportPolicies(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	server.SetPolicies("ROOT_ORGANIZATION_ID", json.RawMessage(`{"policies":[{"id":"policy1","name":"Security-High","threatLevel":9}]}`))
+
+	policies, err := ExportPolicies(server.IQ())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(policies.Policies) != 1 || policies.Policies[0].Name != "Security-High" {
+		t.Fatalf("unexpected policies: %v", policies)
+	}
+
+	policies.Policies[0].Name = "Security-Critical"
+	buf, err := json.Marshal(policies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ImportPolicies(server.IQ(), bytes.NewReader(buf)); err != nil {
+		t.Fatal(err)
+	}
+
+	policies, err = ExportPolicies(server.IQ())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policies.Policies[0].Name != "Security-Critical" {
+		t.Errorf("imported policies were not exported back: %v", policies)
+	}
+}
@@ -0,0 +1,309 @@
+package privateiq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+// ErrPoolClosed is returned when borrowing from an EvaluationPool which was closed
+var ErrPoolClosed = errors.New("evaluation pool closed")
+
+// EvaluationPool keeps temporary applications around to evaluate components with, instead of creating and deleting
+// one for each evaluation. Each application lives in its own temporary organization under the organization whose
+// policies it evaluates with, and is named as by WithTempPrefix.
+// Everything the pool created is deleted by Close
+type EvaluationPool struct {
+	iq      *privateiq
+	maxSize int
+
+	mu       sync.Mutex
+	apps     map[*PooledApplication]bool
+	idle     map[string][]*PooledApplication
+	orgs     map[string]string
+	borrowed int
+	closed   bool
+	leaks    CleanupError
+	// released is closed, and replaced, whenever an application is returned or deleted
+	released chan struct{}
+}
+
+// NewEvaluationPool creates a pool which holds at most the given number of applications, across all organizations
+func NewEvaluationPool(iq publiciq.IQ, maxSize int) *EvaluationPool {
+	if maxSize < 1 {
+		maxSize = 1
+	}
+	return &EvaluationPool{
+		iq:       fromPublicContext(context.Background(), iq),
+		maxSize:  maxSize,
+		apps:     make(map[*PooledApplication]bool),
+		idle:     make(map[string][]*PooledApplication),
+		orgs:     make(map[string]string),
+		released: make(chan struct{}),
+	}
+}
+
+// PooledApplication is a temporary application borrowed from an EvaluationPool, which must be returned to it
+type PooledApplication struct {
+	ID       string
+	PublicID string
+	// OrganizationID is the organization whose policies the application evaluates with
+	OrganizationID string
+
+	pool   *EvaluationPool
+	tmp    *tempApplication
+	broken bool
+	// lent is set while the application is borrowed, under the lock of the pool
+	lent bool
+}
+
+// Evaluate evaluates the list of components with the application
+func (a *PooledApplication) Evaluate(ctx context.Context, components []publiciq.Component) (*publiciq.Evaluation, error) {
+	eval, err := evaluateComponents(ctx, a.pool.iq, components, a.ID)
+	if errors.Is(err, ErrNotFound) {
+		a.broken = true
+	}
+	return eval, err
+}
+
+// Return gives the application back to the pool. It is deleted instead if the pool was closed, or it no longer works.
+// Returning it again, before it is borrowed anew, does nothing
+func (a *PooledApplication) Return() {
+	p := a.pool
+
+	p.mu.Lock()
+	if !a.lent {
+		p.mu.Unlock()
+		return
+	}
+	a.lent = false
+	p.borrowed--
+	if !p.closed && !a.broken {
+		p.idle[a.OrganizationID] = append(p.idle[a.OrganizationID], a)
+		p.notify()
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+
+	p.delete(a)
+}
+
+// notify wakes up whoever waits for an application. The lock must be held
+func (p *EvaluationPool) notify() {
+	close(p.released)
+	p.released = make(chan struct{})
+}
+
+// resolve returns the ID of the organization with the given ID or name
+func (p *EvaluationPool) resolve(ctx context.Context, organization string) (string, error) {
+	p.mu.Lock()
+	id, ok := p.orgs[organization]
+	p.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	id, err := getOrganizationID(p.iq.withContext(ctx), organization)
+	if err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	p.orgs[organization] = id
+	p.mu.Unlock()
+
+	return id, nil
+}
+
+// Borrow returns an application which evaluates with the policies of the organization with the given ID or name.
+// An idle application is checked to still exist before it is handed out. Otherwise one is created,
+// making room by deleting an idle application of another organization if the pool is full.
+// When every application is borrowed, Borrow waits for one to be returned until the context is done
+func (p *EvaluationPool) Borrow(ctx context.Context, organization string) (*PooledApplication, error) {
+	orgID, err := p.resolve(ctx, organization)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, ErrPoolClosed
+		}
+
+		if idle := p.idle[orgID]; len(idle) > 0 {
+			app := idle[len(idle)-1]
+			p.idle[orgID] = idle[:len(idle)-1]
+			p.borrowed++
+			app.lent = true
+			p.mu.Unlock()
+
+			if err := p.check(ctx, app); err != nil {
+				app.Return()
+				if app.broken {
+					continue
+				}
+				return nil, err
+			}
+			return app, nil
+		}
+
+		if len(p.apps) < p.maxSize {
+			app := &PooledApplication{OrganizationID: orgID, pool: p, lent: true}
+			p.apps[app] = true
+			p.borrowed++
+			p.mu.Unlock()
+
+			if err := p.create(ctx, app); err != nil {
+				p.mu.Lock()
+				p.borrowed--
+				app.lent = false
+				p.mu.Unlock()
+				p.delete(app)
+				return nil, err
+			}
+			return app, nil
+		}
+
+		if victim := p.evictable(); victim != nil {
+			p.mu.Unlock()
+			p.delete(victim)
+			continue
+		}
+
+		wait := p.released
+		p.mu.Unlock()
+
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("no pooled application available: %w", ctx.Err())
+		}
+	}
+}
+
+// evictable removes an idle application from the pool, preferring the organization with the most of them. The lock must be held
+func (p *EvaluationPool) evictable() *PooledApplication {
+	var orgID string
+	for id, idle := range p.idle {
+		if len(idle) > len(p.idle[orgID]) {
+			orgID = id
+		}
+	}
+
+	idle := p.idle[orgID]
+	if len(idle) == 0 {
+		return nil
+	}
+	p.idle[orgID] = idle[1:]
+
+	return idle[0]
+}
+
+// check marks the application as broken if it no longer exists
+func (p *EvaluationPool) check(ctx context.Context, app *PooledApplication) error {
+	info, err := getApplicationByPublicID(p.iq.withContext(ctx), app.PublicID)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		app.broken = true
+		return err
+	case err != nil:
+		return err
+	case info.ID != app.ID:
+		app.broken = true
+		return fmt.Errorf("application '%s': %w", app.PublicID, ErrNotFound)
+	}
+	return nil
+}
+
+func (p *EvaluationPool) create(ctx context.Context, app *PooledApplication) error {
+	tmp, err := createTempApplication(ctx, p.iq, app.OrganizationID)
+	app.tmp = tmp
+	if err != nil {
+		return err
+	}
+	app.ID, app.PublicID = tmp.appID, tmp.name
+	return nil
+}
+
+// delete deletes the temporary resources of the application, unless they already were.
+// What could not be deleted is reported by Close
+func (p *EvaluationPool) delete(app *PooledApplication) {
+	p.mu.Lock()
+	if !p.apps[app] {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.apps, app)
+	p.mu.Unlock()
+
+	var cerr *CleanupError
+	if app.tmp != nil && errors.As(app.tmp.cleanup(nil), &cerr) {
+		p.mu.Lock()
+		p.leaks.Leaked = append(p.leaks.Leaked, cerr.Leaked...)
+		p.leaks.Errs = append(p.leaks.Errs, cerr.Errs...)
+		p.mu.Unlock()
+	}
+
+	p.mu.Lock()
+	p.notify()
+	p.mu.Unlock()
+}
+
+// Evaluate evaluates the list of components with an application borrowed for the organization with the given ID or name
+func (p *EvaluationPool) Evaluate(ctx context.Context, organization string, components []publiciq.Component) (*publiciq.Evaluation, error) {
+	app, err := p.Borrow(ctx, organization)
+	if err != nil {
+		return nil, err
+	}
+	defer app.Return()
+
+	return app.Evaluate(ctx, components)
+}
+
+// Close stops lending applications and deletes everything the pool created. It waits for the borrowed applications
+// to be returned until the context is done, and then deletes them regardless.
+// A *CleanupError is returned if anything the pool created could not be deleted
+func (p *EvaluationPool) Close(ctx context.Context) error {
+	p.mu.Lock()
+	p.closed = true
+	p.notify()
+	for p.borrowed > 0 {
+		wait := p.released
+		p.mu.Unlock()
+
+		select {
+		case <-wait:
+		case <-ctx.Done():
+		}
+
+		p.mu.Lock()
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	apps := make([]*PooledApplication, 0, len(p.apps))
+	for app := range p.apps {
+		apps = append(apps, app)
+	}
+	p.idle = make(map[string][]*PooledApplication)
+	p.mu.Unlock()
+
+	for _, app := range apps {
+		p.delete(app)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.leaks.Leaked) > 0 {
+		leaks := p.leaks
+		return &leaks
+	}
+	return nil
+}
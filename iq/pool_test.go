@@ -0,0 +1,106 @@
+package privateiq
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hokiegeek/gonexus-private/iq/privateiqtest"
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+func TestEvaluationPool(t *testing.T) {
+	evaluationPollInterval = time.Millisecond
+
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	payments := server.AddOrganization("Payments", "")
+	components := []publiciq.Component{{Hash: "1234"}}
+	created := func() (n int) {
+		for _, r := range server.Requests() {
+			if r == "POST api/v2/applications" {
+				n++
+			}
+		}
+		return
+	}
+
+	pool := NewEvaluationPool(server.IQ(), 1)
+	ctx := context.Background()
+
+	// Applications are reused
+	for i := 0; i < 3; i++ {
+		if _, err := pool.Evaluate(ctx, publiciq.RootOrganization, components); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := created(); n != 1 {
+		t.Errorf("created %d applications, want 1", n)
+	}
+
+	// An application deleted behind the back of the pool is replaced
+	app, err := pool.Borrow(ctx, publiciq.RootOrganization)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.Return()
+	if err := deleteApplication(FromPublic(server.IQ()), app.ID); err != nil {
+		t.Fatal(err)
+	}
+	if app, err = pool.Borrow(ctx, publiciq.RootOrganization); err != nil {
+		t.Fatal(err)
+	}
+	if n := created(); n != 2 {
+		t.Errorf("created %d applications, want a replacement", n)
+	}
+
+	// A full pool waits for an application to be returned
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Borrow(short, payments.Name); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("borrowing from a full pool returned %v, want context.DeadlineExceeded", err)
+	}
+
+	// Then makes room for another organization
+	app.Return()
+	if app, err = pool.Borrow(ctx, payments.Name); err != nil {
+		t.Fatal(err)
+	}
+	if app.OrganizationID != payments.ID {
+		t.Errorf("borrowed an application for %q, want %q", app.OrganizationID, payments.ID)
+	}
+	var under []string
+	for _, o := range server.Organizations() {
+		if strings.HasPrefix(o.Name, DefaultTempPrefix) {
+			under = append(under, o.ParentOrganizationID)
+		}
+	}
+	if len(under) != 1 || under[0] != payments.ID {
+		t.Errorf("temporary organizations are under %v, want only one under %q", under, payments.ID)
+	}
+	app.Return()
+
+	// Returning twice neither lends the application out twice nor keeps Close from waiting for others
+	app.Return()
+	pool.mu.Lock()
+	if pool.borrowed != 0 || len(pool.idle[payments.ID]) != 1 {
+		t.Errorf("returning twice left %d borrowed and %d idle applications, want 0 and 1", pool.borrowed, len(pool.idle[payments.ID]))
+	}
+	pool.mu.Unlock()
+
+	if err := pool.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if orgs := server.Organizations(); len(orgs) != 2 {
+		t.Errorf("temporary organizations were not deleted: %v", orgs)
+	}
+	if apps := server.Applications(); len(apps) != 0 {
+		t.Errorf("temporary applications were not deleted: %v", apps)
+	}
+	if _, err := pool.Borrow(ctx, publiciq.RootOrganization); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("borrowing from a closed pool returned %v, want ErrPoolClosed", err)
+	}
+}
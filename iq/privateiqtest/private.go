@@ -0,0 +1,738 @@
+package privateiqtest
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+func init() {
+	handle(http.MethodGet, "rest/organization", (*Server).getOrganizationsPrivate)
+	handle(http.MethodPut, "rest/organization/([^/]+)", (*Server).putOrganization)
+	handle(http.MethodDelete, "rest/organization/([^/]+)", (*Server).deleteOrganization)
+	handle(http.MethodPost, "rest/organization/([^/]+)/move/destination/([^/]+)", (*Server).moveOrganization)
+	handle(http.MethodPost, "rest/application/([^/]+)/move/organization/([^/]+)", (*Server).moveApplication)
+	handle(http.MethodGet, "rest/config/webhook", (*Server).getWebhooks)
+	handle(http.MethodPost, "rest/config/webhook", (*Server).postWebhook)
+	handle(http.MethodGet, "rest/label/organization/([^/]+)", (*Server).getLabels)
+	handle(http.MethodPost, "rest/label/organization/([^/]+)", (*Server).postLabel)
+	handle(http.MethodGet, "rest/policy/organization/([^/]+)/export", (*Server).exportPolicies)
+	handle(http.MethodPost, "rest/policy/organization/([^/]+)/import", (*Server).importPolicies)
+	handle(http.MethodGet, "rest/product/license", (*Server).getLicense)
+	handle(http.MethodPost, "rest/product/license", (*Server).postLicense)
+	handle(http.MethodGet, "rest/repositories/([^/]+)/report/details", (*Server).getFirewallReport)
+	handle(http.MethodGet, "rest/policyWaiver/application/([^/]+)/component/([^/]+)", (*Server).getWaivers)
+	handle(http.MethodGet, "rest/policyMonitoring/(application|organization)/([^/]+)", (*Server).getMonitoring)
+	handle(http.MethodPut, "rest/policyMonitoring/(application|organization)/([^/]+)", (*Server).putMonitoring)
+	handle(http.MethodDelete, "rest/policyMonitoring/(application|organization)/([^/]+)", (*Server).deleteMonitoring)
+	handle(http.MethodPost, "rest/tasks/triggerPolicyMonitor", (*Server).triggerMonitoring)
+	handle(http.MethodGet, "rest/config/systemNotice", (*Server).getSystemNotice)
+	handle(http.MethodPut, "rest/config/systemNotice", (*Server).putSystemNotice)
+	handle(http.MethodGet, "rest/config/automaticApplications", (*Server).getAutomaticApplications)
+	handle(http.MethodPut, "rest/config/automaticApplications", (*Server).putAutomaticApplications)
+	handle(http.MethodPost, "rest/report/([^/]+)/([^/]+)/reevaluatePolicy", (*Server).reevaluate)
+	handle(http.MethodGet, "rest/support", (*Server).getSupportZip)
+	handle(http.MethodGet, "rest/vulnerability/details/(cve|sonatype)/([^/]+)", (*Server).getVulnerability)
+}
+
+// Webhook is a webhook configured in the server
+type Webhook struct {
+	ID         string   `json:"id"`
+	URL        string   `json:"url"`
+	SecretKey  string   `json:"secretKey"`
+	EventTypes []string `json:"eventTypes"`
+}
+
+// ComponentLabel is a component label of an organization
+type ComponentLabel struct {
+	ID             string `json:"id"`
+	OwnerID        string `json:"ownerId"`
+	Label          string `json:"label"`
+	LabelLowercase string `json:"labelLowercase"`
+	Description    string `json:"description"`
+	Color          string `json:"color"`
+}
+
+// License is the product license installed in the server
+type License struct {
+	ProductEdition  string   `json:"productEdition"`
+	Fingerprint     string   `json:"fingerprint"`
+	ExpiryTimestamp int64    `json:"expiryTimestamp"`
+	ContactName     string   `json:"contactName"`
+	ContactCompany  string   `json:"contactCompany"`
+	ContactEmail    string   `json:"contactEmail"`
+	Products        []string `json:"products"`
+}
+
+// FirewallComponent is a component listed in the Firewall report of a repository
+type FirewallComponent struct {
+	ComponentID          publiciq.ComponentIdentifier `json:"componentIdentifier"`
+	ComponentDisplayText string                       `json:"componentDisplayText"`
+	Pathname             string                       `json:"pathname"`
+	Hash                 string                       `json:"hash"`
+	MatchState           string                       `json:"matchState"`
+	Quarantined          bool                         `json:"quarantined"`
+	Waived               bool                         `json:"waived"`
+	ThreatLevel          int                          `json:"threatLevel"`
+	HighestThreatLevel   bool                         `json:"highestThreatLevel"`
+	PolicyName           string                       `json:"policyName"`
+}
+
+// Waiver is a policy waiver of a component in an application
+type Waiver struct {
+	ID         string `json:"id"`
+	Hash       string `json:"hash"`
+	PolicyID   string `json:"policyId"`
+	OwnerID    string `json:"ownerId"`
+	Comment    string `json:"comment"`
+	CreateTime int64  `json:"createTime"`
+	PolicyName string `json:"policyName"`
+}
+
+// AutomaticApplications is the configuration of automatic applications
+type AutomaticApplications struct {
+	Enabled              bool   `json:"enabled"`
+	ParentOrganizationID string `json:"parentOrganizationId"`
+}
+
+// SystemNotice is the notice displayed to every user of the server
+type SystemNotice struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+	Enabled bool   `json:"enabled"`
+}
+
+type monitoring struct {
+	ID          string `json:"id"`
+	OwnerID     string `json:"ownerId"`
+	StageTypeID string `json:"stageTypeId"`
+}
+
+var defaultPolicies = json.RawMessage(`{"policies":[],"licenseThreatGroups":[],"licenseThreatGroupLicenses":[],"labels":[],"policyTags":[],"tags":[]}`)
+
+// Webhooks returns the configured webhooks
+func (s *Server) Webhooks() []Webhook {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Webhook(nil), s.webhooks...)
+}
+
+// AddComponentLabel creates a component label in the given organization
+func (s *Server) AddComponentLabel(organizationID string, label ComponentLabel) ComponentLabel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	label.ID = s.nextID()
+	label.OwnerID = organizationID
+	label.LabelLowercase = strings.ToLower(label.Label)
+	s.labels = append(s.labels, label)
+
+	return label
+}
+
+// ComponentLabels returns the component labels of every organization
+func (s *Server) ComponentLabels() []ComponentLabel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ComponentLabel(nil), s.labels...)
+}
+
+// Policies returns the policies last imported into the given organization
+func (s *Server) Policies(organizationID string) json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.policies[organizationID]; ok {
+		return p
+	}
+	return defaultPolicies
+}
+
+// SetPolicies sets the policies exported by the given organization
+func (s *Server) SetPolicies(organizationID string, policies json.RawMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policies[organizationID] = policies
+}
+
+// License returns the installed license
+func (s *Server) License() License {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.license
+}
+
+// SetFirewallComponents sets the components listed in the Firewall report of the given repository
+func (s *Server) SetFirewallComponents(repository string, components []FirewallComponent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.firewall[repository] = components
+}
+
+// AddWaiver waives a policy for a component of the given application
+func (s *Server) AddWaiver(appPublicID string, w Waiver) Waiver {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.ID = s.nextID()
+	w.OwnerID = appPublicID
+	if w.CreateTime == 0 {
+		w.CreateTime = time.Now().UnixNano() / int64(time.Millisecond)
+	}
+	s.waivers = append(s.waivers, w)
+
+	return w
+}
+
+// Monitoring returns the stage continuously monitored for the given application or organization ID
+func (s *Server) Monitoring(ownerID string) (stage string, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stage, ok = s.monitoring[ownerID]
+	return
+}
+
+// MonitoringRuns returns how many times continuous monitoring was triggered
+func (s *Server) MonitoringRuns() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.monitorRuns
+}
+
+// AutomaticApplications returns the configuration of automatic applications
+func (s *Server) AutomaticApplications() AutomaticApplications {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.autoApps
+}
+
+// SystemNotice returns the system notice
+func (s *Server) SystemNotice() SystemNotice {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.notice
+}
+
+// Reevaluations returns the reports which were reevaluated, as "appPublicID/reportID"
+func (s *Server) Reevaluations() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.reevaluations...)
+}
+
+// SetSupportZip sets the archive returned when a support zip is requested, regardless of the contents requested.
+// An empty filename omits the Content-Disposition header
+func (s *Server) SetSupportZip(filename string, content []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.supportZipName, s.supportZip = filename, content
+}
+
+func (s *Server) deleteOrganization(w http.ResponseWriter, r *http.Request, params []string) {
+	id := params[0]
+	if id == publiciq.RootOrganization {
+		http.Error(w, "the Root Organization cannot be deleted", http.StatusBadRequest)
+		return
+	}
+
+	i, ok := s.organization(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	for _, o := range s.organizations {
+		if o.ParentOrganizationID == id {
+			http.Error(w, "organization has child organizations", http.StatusBadRequest)
+			return
+		}
+	}
+	for _, a := range s.applications {
+		if a.OrganizationID == id {
+			http.Error(w, "organization has applications", http.StatusBadRequest)
+			return
+		}
+	}
+
+	s.organizations = append(s.organizations[:i], s.organizations[i+1:]...)
+	delete(s.policies, id)
+	delete(s.monitoring, id)
+	labels := s.labels[:0]
+	for _, l := range s.labels {
+		if l.OwnerID != id {
+			labels = append(labels, l)
+		}
+	}
+	s.labels = labels
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getOrganizationsPrivate(w http.ResponseWriter, r *http.Request, _ []string) {
+	writeJSON(w, http.StatusOK, s.organizations)
+}
+
+// nameInUse reports whether another organization of the parent has the given name
+func (s *Server) nameInUse(name, parentID, exceptID string) bool {
+	for _, o := range s.organizations {
+		if o.Name == name && o.ParentOrganizationID == parentID && o.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) putOrganization(w http.ResponseWriter, r *http.Request, params []string) {
+	i, ok := s.organization(params[0])
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	var req Organization
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	org := s.organizations[i]
+	switch {
+	case org.ID == publiciq.RootOrganization:
+		http.Error(w, "the Root Organization cannot be renamed", http.StatusBadRequest)
+	case req.Name == "":
+		http.Error(w, "organization name is required", http.StatusBadRequest)
+	case s.nameInUse(req.Name, org.ParentOrganizationID, org.ID):
+		http.Error(w, "organization name already in use", http.StatusBadRequest)
+	default:
+		s.organizations[i].Name = req.Name
+		writeJSON(w, http.StatusOK, s.organizations[i])
+	}
+}
+
+func (s *Server) moveOrganization(w http.ResponseWriter, r *http.Request, params []string) {
+	i, ok := s.organization(params[0])
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if _, ok := s.organization(params[1]); !ok {
+		http.Error(w, "destination organization not found", http.StatusNotFound)
+		return
+	}
+
+	org := s.organizations[i]
+	if org.ID == publiciq.RootOrganization {
+		http.Error(w, "the Root Organization cannot be moved", http.StatusBadRequest)
+		return
+	}
+
+	// The destination may not be the organization itself or one of its descendants
+	for id := params[1]; id != ""; {
+		if id == org.ID {
+			http.Error(w, "an organization cannot be moved under itself", http.StatusBadRequest)
+			return
+		}
+		p, _ := s.organization(id)
+		id = s.organizations[p].ParentOrganizationID
+	}
+
+	if s.nameInUse(org.Name, params[1], org.ID) {
+		http.Error(w, "organization name already in use in the destination", http.StatusBadRequest)
+		return
+	}
+
+	s.organizations[i].ParentOrganizationID = params[1]
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) moveApplication(w http.ResponseWriter, r *http.Request, params []string) {
+	i, ok := s.application(byID(params[0]))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if _, ok := s.organization(params[1]); !ok {
+		http.Error(w, "destination organization not found", http.StatusNotFound)
+		return
+	}
+
+	s.applications[i].OrganizationID = params[1]
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getWebhooks(w http.ResponseWriter, r *http.Request, _ []string) {
+	writeJSON(w, http.StatusOK, append([]Webhook{}, s.webhooks...))
+}
+
+func (s *Server) postWebhook(w http.ResponseWriter, r *http.Request, _ []string) {
+	var hook Webhook
+	if !readJSON(w, r, &hook) {
+		return
+	}
+	hook.ID = s.nextID()
+	s.webhooks = append(s.webhooks, hook)
+
+	writeJSON(w, http.StatusOK, hook)
+}
+
+func (s *Server) getLabels(w http.ResponseWriter, r *http.Request, params []string) {
+	labels := make([]ComponentLabel, 0)
+	for _, l := range s.labels {
+		if l.OwnerID == params[0] {
+			labels = append(labels, l)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, labels)
+}
+
+func (s *Server) postLabel(w http.ResponseWriter, r *http.Request, params []string) {
+	if _, ok := s.organization(params[0]); !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	var label ComponentLabel
+	if !readJSON(w, r, &label) {
+		return
+	}
+	label.ID = s.nextID()
+	label.OwnerID = params[0]
+	label.LabelLowercase = strings.ToLower(label.Label)
+	s.labels = append(s.labels, label)
+
+	writeJSON(w, http.StatusOK, label)
+}
+
+func (s *Server) exportPolicies(w http.ResponseWriter, r *http.Request, params []string) {
+	if _, ok := s.organization(params[0]); !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	policies, ok := s.policies[params[0]]
+	if !ok {
+		policies = defaultPolicies
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(policies)
+}
+
+// readFormFile returns the content of the "file" field of a multipart form
+func readFormFile(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	defer file.Close()
+
+	buf, err := ioutil.ReadAll(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	return buf, true
+}
+
+func (s *Server) importPolicies(w http.ResponseWriter, r *http.Request, params []string) {
+	if _, ok := s.organization(params[0]); !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	buf, ok := readFormFile(w, r)
+	if !ok {
+		return
+	}
+	if !json.Valid(buf) {
+		http.Error(w, "invalid policies file", http.StatusBadRequest)
+		return
+	}
+	s.policies[params[0]] = json.RawMessage(buf)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getLicense(w http.ResponseWriter, r *http.Request, _ []string) {
+	writeJSON(w, http.StatusOK, s.license)
+}
+
+func (s *Server) postLicense(w http.ResponseWriter, r *http.Request, _ []string) {
+	buf, ok := readFormFile(w, r)
+	if !ok {
+		return
+	}
+
+	sum := sha1.Sum(buf)
+	s.license.Fingerprint = hex.EncodeToString(sum[:])
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getFirewallReport(w http.ResponseWriter, r *http.Request, params []string) {
+	components, ok := s.firewall[params[0]]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Versions before 1.99 return the whole report, whatever the query
+	query := r.URL.Query()
+	if query.Get("page") == "" || s.olderThan(99) {
+		writeJSON(w, http.StatusOK, append([]FirewallComponent{}, components...))
+		return
+	}
+
+	filtered := make([]FirewallComponent, 0, len(components))
+	for _, c := range components {
+		if firewallMatches(query, c) {
+			filtered = append(filtered, c)
+		}
+	}
+
+	if by := query.Get("sortBy"); by != "" {
+		asc := query.Get("asc") == "true"
+		sort.SliceStable(filtered, func(i, j int) bool {
+			a, b := filtered[i], filtered[j]
+			if !asc {
+				a, b = b, a
+			}
+			switch by {
+			case "threatLevel":
+				return a.ThreatLevel < b.ThreatLevel
+			case "policyName":
+				return a.PolicyName < b.PolicyName
+			}
+			return a.ComponentDisplayText < b.ComponentDisplayText
+		})
+	}
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	size, _ := strconv.Atoi(query.Get("pageSize"))
+	if page < 1 || size < 1 {
+		http.Error(w, "invalid page", http.StatusBadRequest)
+		return
+	}
+	start, end := (page-1)*size, page*size
+	if start > len(filtered) {
+		start = len(filtered)
+	}
+	if end > len(filtered) {
+		end = len(filtered)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"page":     page,
+		"pageSize": size,
+		"total":    len(filtered),
+		"results":  append([]FirewallComponent{}, filtered[start:end]...),
+	})
+}
+
+// olderThan reports whether the version of the server is older than the given minor release of IQ 1
+func (s *Server) olderThan(minor int) bool {
+	parts := strings.Split(s.version, ".")
+	if len(parts) < 2 {
+		return false
+	}
+	n, err := strconv.Atoi(parts[1])
+	return err == nil && parts[0] == "1" && n < minor
+}
+
+func firewallMatches(query url.Values, c FirewallComponent) bool {
+	if query.Get("quarantined") == "true" && !c.Quarantined {
+		return false
+	}
+	if waived := query.Get("waived"); waived != "" && strconv.FormatBool(c.Waived) != waived {
+		return false
+	}
+	if policy := query.Get("policyName"); policy != "" && c.PolicyName != policy {
+		return false
+	}
+	if min, err := strconv.Atoi(query.Get("minThreatLevel")); err == nil && c.ThreatLevel < min {
+		return false
+	}
+	if max, err := strconv.Atoi(query.Get("maxThreatLevel")); err == nil && c.ThreatLevel > max {
+		return false
+	}
+	return true
+}
+
+func (s *Server) getWaivers(w http.ResponseWriter, r *http.Request, params []string) {
+	waivers := make([]Waiver, 0)
+	for _, waiver := range s.waivers {
+		if waiver.OwnerID == params[0] && waiver.Hash == params[1] {
+			waivers = append(waivers, waiver)
+		}
+	}
+
+	type waiversByOwner struct {
+		OwnerID   string   `json:"ownerId"`
+		OwnerName string   `json:"ownerName"`
+		OwnerType string   `json:"ownerType"`
+		Waivers   []Waiver `json:"waivers"`
+	}
+	byOwner := make([]waiversByOwner, 0)
+	if len(waivers) > 0 {
+		byOwner = append(byOwner, waiversByOwner{params[0], params[0], "application", waivers})
+	}
+
+	writeJSON(w, http.StatusOK, map[string][]waiversByOwner{"waiversByOwner": byOwner})
+}
+
+func (s *Server) monitoringOwnerExists(ownerType, id string) bool {
+	if ownerType == "application" {
+		_, ok := s.application(byID(id))
+		return ok
+	}
+	_, ok := s.organization(id)
+	return ok
+}
+
+func (s *Server) getMonitoring(w http.ResponseWriter, r *http.Request, params []string) {
+	stage, ok := s.monitoring[params[1]]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, monitoring{ID: params[1], OwnerID: params[1], StageTypeID: stage})
+}
+
+func (s *Server) putMonitoring(w http.ResponseWriter, r *http.Request, params []string) {
+	if !s.monitoringOwnerExists(params[0], params[1]) {
+		http.NotFound(w, r)
+		return
+	}
+
+	var req monitoring
+	if !readJSON(w, r, &req) {
+		return
+	}
+	s.monitoring[params[1]] = req.StageTypeID
+
+	writeJSON(w, http.StatusOK, monitoring{ID: params[1], OwnerID: params[1], StageTypeID: req.StageTypeID})
+}
+
+func (s *Server) deleteMonitoring(w http.ResponseWriter, r *http.Request, params []string) {
+	if !s.monitoringOwnerExists(params[0], params[1]) {
+		http.NotFound(w, r)
+		return
+	}
+	delete(s.monitoring, params[1])
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) triggerMonitoring(w http.ResponseWriter, r *http.Request, _ []string) {
+	s.monitorRuns++
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getSystemNotice(w http.ResponseWriter, r *http.Request, _ []string) {
+	writeJSON(w, http.StatusOK, s.notice)
+}
+
+func (s *Server) putSystemNotice(w http.ResponseWriter, r *http.Request, _ []string) {
+	var notice SystemNotice
+	if !readJSON(w, r, &notice) {
+		return
+	}
+	s.notice = notice
+
+	writeJSON(w, http.StatusOK, notice)
+}
+
+func (s *Server) getAutomaticApplications(w http.ResponseWriter, r *http.Request, _ []string) {
+	writeJSON(w, http.StatusOK, s.autoApps)
+}
+
+func (s *Server) putAutomaticApplications(w http.ResponseWriter, r *http.Request, _ []string) {
+	var req AutomaticApplications
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Enabled {
+		if _, ok := s.organization(req.ParentOrganizationID); !ok {
+			http.Error(w, "organization not found", http.StatusNotFound)
+			return
+		}
+	}
+	s.autoApps = req
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) reevaluate(w http.ResponseWriter, r *http.Request, params []string) {
+	for _, rep := range s.reports[params[0]] {
+		if rep.ID == params[1] {
+			s.reevaluations = append(s.reevaluations, params[0]+"/"+params[1])
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	http.NotFound(w, r)
+}
+
+// supportZipFiles are the files of the support zip generated by default, by the query parameter which includes them
+var supportZipFiles = []struct {
+	param, name, content string
+}{
+	{"includeLogs", "log/clm-server.log", "2020-01-01 00:00:00,000+0000 INFO [main] admin com.sonatype.insight.brain.service.InsightBrainService - Started on iq.example.com\n"},
+	{"includeConfig", "config/config.yml", "baseUrl: http://iq.example.com:8070/\n"},
+	{"includeConfig", "config/ldap.json", `{"hostname":"ldap.example.com","systemUsername":"cn=iq,ou=services,dc=example,dc=com","systemPassword":"s3cr3t"}` + "\n"},
+	{"includeSysInfo", "sysinfo/sysinfo.json", `{"hostname":"iq.example.com"}` + "\n"},
+	{"includeMetrics", "metrics/metrics.json", `{"gauges":{"jvm.memory.heap.used":{"value":1024}}}` + "\n"},
+}
+
+// generateSupportZip creates a support zip with the files selected by the query, all of them if it selects none
+func generateSupportZip(query url.Values) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range supportZipFiles {
+		if query.Get(f.param) == "false" {
+			continue
+		}
+		fw, _ := zw.Create(f.name)
+		fw.Write([]byte(f.content))
+	}
+	zw.Close()
+
+	return buf.Bytes()
+}
+
+func (s *Server) getSupportZip(w http.ResponseWriter, r *http.Request, _ []string) {
+	content := s.supportZip
+	if content == nil {
+		content = generateSupportZip(r.URL.Query())
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	if s.supportZipName != "" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", s.supportZipName))
+	}
+	w.Write(content)
+}
+
+func (s *Server) getVulnerability(w http.ResponseWriter, r *http.Request, params []string) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"refId":       params[1],
+		"source":      params[0],
+		"htmlDetails": fmt.Sprintf("<h2>%s</h2>", params[1]),
+	})
+}
@@ -0,0 +1,344 @@
+package privateiqtest
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+func init() {
+	handle(http.MethodGet, "api/v2/organizations", (*Server).getOrganizations)
+	handle(http.MethodPost, "api/v2/organizations", (*Server).postOrganization)
+	handle(http.MethodGet, "api/v2/applications", (*Server).getApplications)
+	handle(http.MethodPost, "api/v2/applications", (*Server).postApplication)
+	handle(http.MethodDelete, "api/v2/applications/([^/]+)", (*Server).deleteApplication)
+	handle(http.MethodGet, "api/v2/reports/applications", (*Server).getReportInfos)
+	handle(http.MethodGet, "api/v2/reports/applications/([^/]+)", (*Server).getReportInfos)
+	handle(http.MethodGet, "api/v2/applications/([^/]+)/reports/([^/]+)/raw", (*Server).getRawReport)
+	handle(http.MethodPost, "api/v2/evaluation/applications/([^/]+)", (*Server).postEvaluation)
+	handle(http.MethodGet, "api/v2/evaluation/applications/([^/]+)/results/([^/]+)", (*Server).getEvaluation)
+}
+
+// Organization is an organization of the server
+type Organization struct {
+	ID                   string `json:"id"`
+	Name                 string `json:"name"`
+	ParentOrganizationID string `json:"parentOrganizationId,omitempty"`
+}
+
+// Application is an application of the server
+type Application struct {
+	ID             string `json:"id"`
+	PublicID       string `json:"publicId"`
+	Name           string `json:"name"`
+	OrganizationID string `json:"organizationId"`
+}
+
+type report struct {
+	ID         string
+	Stage      string
+	Components []publiciq.Component
+}
+
+// KnownComponent describes how the server evaluates a component which it recognizes by hash or package URL
+type KnownComponent struct {
+	Component  publiciq.Component
+	Violations []publiciq.PolicyViolation
+}
+
+// AddOrganization creates an organization under the given parent, or the Root Organization if empty
+func (s *Server) AddOrganization(name, parentID string) Organization {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if parentID == "" {
+		parentID = publiciq.RootOrganization
+	}
+	org := Organization{ID: s.nextID(), Name: name, ParentOrganizationID: parentID}
+	s.organizations = append(s.organizations, org)
+
+	return org
+}
+
+// Organizations returns the organizations of the server, including the Root Organization
+func (s *Server) Organizations() []Organization {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Organization(nil), s.organizations...)
+}
+
+// AddApplication creates an application in the given organization
+func (s *Server) AddApplication(publicID, name, organizationID string) Application {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	app := Application{ID: s.nextID(), PublicID: publicID, Name: name, OrganizationID: organizationID}
+	s.applications = append(s.applications, app)
+
+	return app
+}
+
+// Applications returns the applications of the server
+func (s *Server) Applications() []Application {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Application(nil), s.applications...)
+}
+
+// AddReport stores a report of the given application and stage containing the given components, returning its ID
+func (s *Server) AddReport(appPublicID, stage string, components ...publiciq.Component) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextID()
+	s.reports[appPublicID] = append(s.reports[appPublicID], report{ID: id, Stage: stage, Components: components})
+
+	return id
+}
+
+// AddKnownComponent makes the server recognize the component, by hash, package URL and component identifier, when evaluating it
+func (s *Server) AddKnownComponent(c KnownComponent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c.Component.Hash != "" {
+		s.known[c.Component.Hash] = c
+	}
+	if c.Component.PackageURL != "" {
+		s.known[c.Component.PackageURL] = c
+	}
+	if c.Component.ComponentID != nil {
+		s.known[c.Component.ComponentID.String()] = c
+	}
+}
+
+func (s *Server) organization(id string) (int, bool) {
+	for i, o := range s.organizations {
+		if o.ID == id {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+func (s *Server) application(match func(Application) bool) (int, bool) {
+	for i, a := range s.applications {
+		if match(a) {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+func byID(id string) func(Application) bool {
+	return func(a Application) bool { return a.ID == id }
+}
+
+func byPublicID(id string) func(Application) bool {
+	return func(a Application) bool { return a.PublicID == id }
+}
+
+func (s *Server) getOrganizations(w http.ResponseWriter, r *http.Request, _ []string) {
+	writeJSON(w, http.StatusOK, map[string][]Organization{"organizations": s.organizations})
+}
+
+func (s *Server) postOrganization(w http.ResponseWriter, r *http.Request, _ []string) {
+	var req Organization
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	if req.ParentOrganizationID == "" {
+		req.ParentOrganizationID = publiciq.RootOrganization
+	}
+	if _, ok := s.organization(req.ParentOrganizationID); !ok {
+		http.Error(w, "parent organization not found", http.StatusNotFound)
+		return
+	}
+	for _, o := range s.organizations {
+		if o.Name == req.Name && o.ParentOrganizationID == req.ParentOrganizationID {
+			http.Error(w, "organization name already in use", http.StatusBadRequest)
+			return
+		}
+	}
+
+	org := Organization{ID: s.nextID(), Name: req.Name, ParentOrganizationID: req.ParentOrganizationID}
+	s.organizations = append(s.organizations, org)
+
+	writeJSON(w, http.StatusOK, org)
+}
+
+func (s *Server) getApplications(w http.ResponseWriter, r *http.Request, _ []string) {
+	apps := make([]Application, 0)
+	publicID := r.URL.Query().Get("publicId")
+	for _, a := range s.applications {
+		if publicID == "" || a.PublicID == publicID {
+			apps = append(apps, a)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string][]Application{"applications": apps})
+}
+
+func (s *Server) postApplication(w http.ResponseWriter, r *http.Request, _ []string) {
+	var req Application
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	if _, ok := s.organization(req.OrganizationID); !ok {
+		http.Error(w, "organization not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := s.application(byPublicID(req.PublicID)); ok {
+		http.Error(w, "application public id already in use", http.StatusBadRequest)
+		return
+	}
+
+	app := Application{ID: s.nextID(), PublicID: req.PublicID, Name: req.Name, OrganizationID: req.OrganizationID}
+	s.applications = append(s.applications, app)
+
+	writeJSON(w, http.StatusOK, app)
+}
+
+func (s *Server) deleteApplication(w http.ResponseWriter, r *http.Request, params []string) {
+	i, ok := s.application(byID(params[0]))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	app := s.applications[i]
+	s.applications = append(s.applications[:i], s.applications[i+1:]...)
+	delete(s.reports, app.PublicID)
+	delete(s.monitoring, app.ID)
+	waivers := s.waivers[:0]
+	for _, w := range s.waivers {
+		if w.OwnerID != app.PublicID {
+			waivers = append(waivers, w)
+		}
+	}
+	s.waivers = waivers
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) reportInfo(app Application, rep report) publiciq.ReportInfo {
+	return publiciq.ReportInfo{
+		ApplicationID:     app.ID,
+		Stage:             rep.Stage,
+		EvaluationDateStr: time.Now().Format(time.RFC3339),
+		ReportDataURL:     fmt.Sprintf("api/v2/applications/%s/reports/%s/raw", app.PublicID, rep.ID),
+		ReportHTMLURL:     fmt.Sprintf("ui/links/application/%s/report/%s", app.PublicID, rep.ID),
+	}
+}
+
+func (s *Server) getReportInfos(w http.ResponseWriter, r *http.Request, params []string) {
+	infos := make([]publiciq.ReportInfo, 0)
+	for _, app := range s.applications {
+		if len(params) > 0 && app.ID != params[0] {
+			continue
+		}
+		for _, rep := range s.reports[app.PublicID] {
+			infos = append(infos, s.reportInfo(app, rep))
+		}
+	}
+
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func (s *Server) getRawReport(w http.ResponseWriter, r *http.Request, params []string) {
+	for _, rep := range s.reports[params[0]] {
+		if rep.ID != params[1] {
+			continue
+		}
+
+		type rawComponent struct {
+			publiciq.Component
+		}
+		components := make([]rawComponent, len(rep.Components))
+		for i, c := range rep.Components {
+			components[i] = rawComponent{c}
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"components": components,
+			"matchSummary": map[string]int{
+				"knownComponentCount": len(components),
+				"totalComponentCount": len(components),
+			},
+		})
+		return
+	}
+
+	http.NotFound(w, r)
+}
+
+func (s *Server) evaluate(c publiciq.Component) publiciq.ComponentEvaluationResult {
+	var result publiciq.ComponentEvaluationResult
+
+	known, ok := s.known[c.Hash]
+	if !ok || c.Hash == "" {
+		known, ok = s.known[c.PackageURL]
+	}
+	if !ok && c.ComponentID != nil {
+		known, ok = s.known[c.ComponentID.String()]
+	}
+	if !ok || (c.Hash == "" && c.PackageURL == "" && c.ComponentID == nil) {
+		result.Component = c
+		result.MatchState = "unknown"
+		return result
+	}
+
+	result.Component = known.Component
+	result.MatchState = "exact"
+	result.PolicyData.PolicyViolations = known.Violations
+
+	return result
+}
+
+func (s *Server) postEvaluation(w http.ResponseWriter, r *http.Request, params []string) {
+	if _, ok := s.application(byID(params[0])); !ok {
+		http.Error(w, "application not found", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		Components []publiciq.Component `json:"components"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	eval := publiciq.Evaluation{
+		ApplicationID:  params[0],
+		SubmittedDate:  time.Now().Format(time.RFC3339),
+		EvaluationDate: time.Now().Format(time.RFC3339),
+		Results:        make([]publiciq.ComponentEvaluationResult, len(req.Components)),
+	}
+	for i, c := range req.Components {
+		eval.Results[i] = s.evaluate(c)
+	}
+
+	resultID := s.nextID()
+	s.evaluations[resultID] = eval
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"resultId":      resultID,
+		"submittedDate": eval.SubmittedDate,
+		"applicationId": params[0],
+		"resultsUrl":    fmt.Sprintf("api/v2/evaluation/applications/%s/results/%s", params[0], resultID),
+	})
+}
+
+func (s *Server) getEvaluation(w http.ResponseWriter, r *http.Request, params []string) {
+	eval, ok := s.evaluations[params[1]]
+	if !ok || eval.ApplicationID != params[0] {
+		http.NotFound(w, r)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, eval)
+}
@@ -0,0 +1,284 @@
+// Package privateiqtest provides an in-memory IQ server which emulates the private APIs used by privateiq,
+// along with the public APIs they depend on, so that code using privateiq can be tested offline.
+//
+// The server keeps state: whatever is created through the APIs can be read back through them,
+// or inspected and seeded through the methods of Server.
+package privateiqtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+// The credentials accepted by a Server
+const (
+	Username = "admin"
+	Password = "admin123"
+)
+
+// DefaultVersion is the IQ version reported by a new Server
+const DefaultVersion = "1.100.0-01"
+
+const (
+	sessionCookie = "CLMSESSIONID"
+	csrfCookie    = "CLM-CSRF-TOKEN"
+	csrfHeader    = "X-CSRF-TOKEN"
+)
+
+type route struct {
+	method  string
+	pattern *regexp.Regexp
+	handler func(s *Server, w http.ResponseWriter, r *http.Request, params []string)
+}
+
+var routes []route
+
+// handle registers a handler for the method and path pattern, given without the leading slash
+func handle(method, pattern string, handler func(s *Server, w http.ResponseWriter, r *http.Request, params []string)) {
+	routes = append(routes, route{method, regexp.MustCompile("^" + pattern + "$"), handler})
+}
+
+func init() {
+	handle(http.MethodGet, "rest/user/session", (*Server).getSession)
+	handle(http.MethodGet, "rest/product/version", (*Server).getVersion)
+}
+
+// Server is a fake IQ server backed by an httptest.Server
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	ids      int
+	requests []string
+	sessions map[string]string
+	tokens   map[string]string
+	version  string
+
+	organizations  []Organization
+	applications   []Application
+	reports        map[string][]report
+	evaluations    map[string]publiciq.Evaluation
+	known          map[string]KnownComponent
+	webhooks       []Webhook
+	labels         []ComponentLabel
+	policies       map[string]json.RawMessage
+	license        License
+	firewall       map[string][]FirewallComponent
+	waivers        []Waiver
+	monitoring     map[string]string
+	autoApps       AutomaticApplications
+	notice         SystemNotice
+	reevaluations  []string
+	monitorRuns    int
+	supportZipName string
+	supportZip     []byte
+}
+
+// NewServer starts a fake IQ server which only contains the Root Organization.
+// The server should be closed when no longer needed
+func NewServer() *Server {
+	s := &Server{
+		version:     DefaultVersion,
+		sessions:    make(map[string]string),
+		tokens:      make(map[string]string),
+		reports:     make(map[string][]report),
+		evaluations: make(map[string]publiciq.Evaluation),
+		known:       make(map[string]KnownComponent),
+		policies:    make(map[string]json.RawMessage),
+		firewall:    make(map[string][]FirewallComponent),
+		monitoring:  make(map[string]string),
+		organizations: []Organization{
+			{ID: publiciq.RootOrganization, Name: "Root Organization"},
+		},
+		license: License{
+			ProductEdition: "Enterprise",
+			Fingerprint:    "dummy-fingerprint",
+			ContactName:    "Dummy Contact",
+			Products:       []string{"clm", "firewall"},
+		},
+	}
+	s.supportZipName = "support-20200101-000000.zip"
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+
+	return s
+}
+
+// IQ returns a public IQ instance which connects to the server
+func (s *Server) IQ() publiciq.IQ {
+	iq, _ := publiciq.New(s.URL, Username, Password)
+	return iq
+}
+
+// Requests returns every request received by the server as "METHOD path?query", without the leading slash
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// ExpireSessions invalidates every private session, as IQ does when they time out
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = make(map[string]string)
+}
+
+// AddUserToken creates a user token, returning the user code and pass code which authenticate with it
+func (s *Server) AddUserToken() (string, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	userCode, passCode := s.nextID()[24:], s.nextID()
+	s.tokens[userCode] = passCode
+
+	return userCode, passCode
+}
+
+// NewSession establishes a private session, as an SSO login would, returning the cookies which identify it
+func (s *Server) NewSession() []*http.Cookie {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, csrf := s.nextID(), s.nextID()
+	s.sessions[id] = csrf
+
+	return []*http.Cookie{
+		{Name: sessionCookie, Value: id, Path: "/"},
+		{Name: csrfCookie, Value: csrf, Path: "/"},
+	}
+}
+
+// SetVersion sets the IQ version reported by the server, such as 1.85.0-01
+func (s *Server) SetVersion(version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version = version
+}
+
+// nextID returns a new identifier which looks like the ones generated by IQ
+func (s *Server) nextID() string {
+	s.ids++
+	return fmt.Sprintf("%032x", s.ids)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/")
+
+	s.mu.Lock()
+	s.requests = append(s.requests, strings.TrimSuffix(r.Method+" "+path+"?"+r.URL.RawQuery, "?"))
+	s.mu.Unlock()
+
+	if status, msg := s.authorize(r, path); status != http.StatusOK {
+		http.Error(w, msg, status)
+		return
+	}
+
+	var pathMatched bool
+	for _, rt := range routes {
+		params := rt.pattern.FindStringSubmatch(path)
+		if params == nil {
+			continue
+		}
+		pathMatched = true
+		if rt.method != r.Method {
+			continue
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		rt.handler(s, w, r, params[1:])
+		return
+	}
+
+	if pathMatched {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// authorize checks the credentials, or user token, of the public API or the session, and CSRF token, of the private API.
+// The public API also accepts a session, as IQ does for its UI
+func (s *Server) authorize(r *http.Request, path string) (int, string) {
+	_, hasCookies := r.Header["Cookie"]
+	if (!strings.HasPrefix(path, "rest/") && !hasCookies) || path == "rest/user/session" {
+		if user, pass, ok := r.BasicAuth(); !ok || !s.validCredentials(user, pass) {
+			return http.StatusUnauthorized, "invalid credentials"
+		}
+		return http.StatusOK, ""
+	}
+
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return http.StatusUnauthorized, "no session"
+	}
+
+	s.mu.Lock()
+	csrf, ok := s.sessions[cookie.Value]
+	s.mu.Unlock()
+	if !ok {
+		return http.StatusUnauthorized, "session expired"
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		if token, err := r.Cookie(csrfCookie); err != nil || token.Value != csrf || r.Header.Get(csrfHeader) != csrf {
+			return http.StatusForbidden, "invalid CSRF token"
+		}
+	}
+
+	return http.StatusOK, ""
+}
+
+func (s *Server) validCredentials(user, pass string) bool {
+	if user == Username && pass == Password {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	passCode, ok := s.tokens[user]
+	return ok && passCode == pass
+}
+
+func (s *Server) getSession(w http.ResponseWriter, r *http.Request, _ []string) {
+	id, csrf := s.nextID(), s.nextID()
+	s.sessions[id] = csrf
+
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: id, Path: "/"})
+	http.SetCookie(w, &http.Cookie{Name: csrfCookie, Value: csrf, Path: "/"})
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) getVersion(w http.ResponseWriter, r *http.Request, _ []string) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"name":    "Nexus IQ Server",
+		"version": s.version,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(buf)
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,214 @@
+package privateiq
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+// The public endpoints which the package queries itself so that their errors are reported as an *APIError
+const (
+	restOrganizations         = "api/v2/organizations"
+	restApplications          = "api/v2/applications"
+	restApplication           = "api/v2/applications/%s"
+	restApplicationByPublicID = "api/v2/applications?publicId=%s"
+	restReportInfos           = "api/v2/reports/applications/%s"
+	// The form of the data URL of a report
+	restReportRaw = "api/v2/applications/%s/reports/%s/raw"
+)
+
+// getAllOrganizations behaves like publiciq.GetAllOrganizations
+func getAllOrganizations(iq publiciq.IQ) ([]publiciq.Organization, error) {
+	body, _, err := iq.Get(restOrganizations)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve organizations: %w", err)
+	}
+
+	var resp struct {
+		Organizations []publiciq.Organization `json:"organizations"`
+	}
+	if err = json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("could not parse organizations: %w", err)
+	}
+
+	return resp.Organizations, nil
+}
+
+// getOrganizationByName behaves like publiciq.GetOrganizationByName
+func getOrganizationByName(iq publiciq.IQ, organizationName string) (*publiciq.Organization, error) {
+	orgs, err := getAllOrganizations(iq)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, org := range orgs {
+		if org.Name == organizationName {
+			return &org, nil
+		}
+	}
+
+	return nil, fmt.Errorf("organization '%s': %w", organizationName, ErrNotFound)
+}
+
+// getOrganization returns the organization with the given ID or, failing that, name
+func getOrganization(iq publiciq.IQ, organization string) (*publiciq.Organization, error) {
+	orgs, err := getAllOrganizations(iq)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, org := range orgs {
+		if org.ID == organization {
+			return &org, nil
+		}
+	}
+	for _, org := range orgs {
+		if org.Name == organization {
+			return &org, nil
+		}
+	}
+
+	return nil, fmt.Errorf("organization '%s': %w", organization, ErrNotFound)
+}
+
+// getOrganizationID returns the ID of the organization with the given ID or name, sparing a request for the Root Organization
+func getOrganizationID(iq publiciq.IQ, organization string) (string, error) {
+	if organization == publiciq.RootOrganization {
+		return organization, nil
+	}
+
+	org, err := getOrganization(iq, organization)
+	if err != nil {
+		return "", err
+	}
+
+	return org.ID, nil
+}
+
+// getApplicationByPublicID behaves like publiciq.GetApplicationByPublicID
+func getApplicationByPublicID(iq publiciq.IQ, applicationPublicID string) (*publiciq.Application, error) {
+	body, _, err := iq.Get(fmt.Sprintf(restApplicationByPublicID, applicationPublicID))
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve application '%s': %w", applicationPublicID, err)
+	}
+
+	var resp struct {
+		Applications []publiciq.Application `json:"applications"`
+	}
+	if err = json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("could not parse application '%s': %w", applicationPublicID, err)
+	}
+
+	if len(resp.Applications) == 0 {
+		return nil, fmt.Errorf("application '%s': %w", applicationPublicID, ErrNotFound)
+	}
+
+	return &resp.Applications[0], nil
+}
+
+// deleteApplication behaves like publiciq.DeleteApplication
+func deleteApplication(iq publiciq.IQ, applicationID string) error {
+	if _, err := iq.Del(fmt.Sprintf(restApplication, applicationID)); err != nil {
+		return fmt.Errorf("application '%s' not deleted: %w", applicationID, err)
+	}
+	return nil
+}
+
+// createOrganization behaves like publiciq.CreateOrganization, but creates the organization under the given parent
+func createOrganization(iq publiciq.IQ, name, parentID string) (string, error) {
+	request, err := json.Marshal(organizationJSON{Name: name, ParentOrganizationID: parentID})
+	if err != nil {
+		return "", err
+	}
+
+	body, _, err := iq.Post(restOrganizations, bytes.NewBuffer(request))
+	if err != nil {
+		return "", fmt.Errorf("organization '%s' not created: %w", name, err)
+	}
+
+	var org publiciq.Organization
+	if err = json.Unmarshal(body, &org); err != nil {
+		return "", fmt.Errorf("organization '%s' not created: %w", name, err)
+	}
+
+	return org.ID, nil
+}
+
+// createApplication behaves like publiciq.CreateApplication
+func createApplication(iq publiciq.IQ, name, publicID, organizationID string) (string, error) {
+	request, err := json.Marshal(publiciq.Application{Name: name, PublicID: publicID, OrganizationID: organizationID})
+	if err != nil {
+		return "", err
+	}
+
+	body, _, err := iq.Post(restApplications, bytes.NewBuffer(request))
+	if err != nil {
+		return "", fmt.Errorf("application '%s' not created: %w", publicID, err)
+	}
+
+	var app publiciq.Application
+	if err = json.Unmarshal(body, &app); err != nil {
+		return "", fmt.Errorf("application '%s' not created: %w", publicID, err)
+	}
+
+	return app.ID, nil
+}
+
+// getAllApplications behaves like publiciq.GetAllApplications
+func getAllApplications(iq publiciq.IQ) ([]publiciq.Application, error) {
+	body, _, err := iq.Get(restApplications)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve applications: %w", err)
+	}
+
+	var resp struct {
+		Applications []publiciq.Application `json:"applications"`
+	}
+	if err = json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("could not parse applications: %w", err)
+	}
+
+	return resp.Applications, nil
+}
+
+// getRawReportByAppID behaves like publiciq.GetRawReportByAppID. An error matching ErrNotFound is returned
+// when the application has no report for the stage
+func getRawReportByAppID(iq publiciq.IQ, appID, stage string) (publiciq.ReportRaw, error) {
+	app, err := getApplicationByPublicID(iq, appID)
+	if err != nil {
+		return publiciq.ReportRaw{}, err
+	}
+
+	body, _, err := iq.Get(fmt.Sprintf(restReportInfos, app.ID))
+	if err != nil {
+		return publiciq.ReportRaw{}, fmt.Errorf("could not retrieve reports of application '%s': %w", appID, err)
+	}
+
+	var infos []publiciq.ReportInfo
+	if err = json.Unmarshal(body, &infos); err != nil {
+		return publiciq.ReportRaw{}, fmt.Errorf("could not parse reports of application '%s': %w", appID, err)
+	}
+
+	for _, info := range infos {
+		if info.Stage != stage {
+			continue
+		}
+
+		body, _, err := iq.Get(info.ReportDataURL)
+		if err != nil {
+			return publiciq.ReportRaw{}, fmt.Errorf("could not retrieve %s report of application '%s': %w", stage, appID, err)
+		}
+
+		var report publiciq.ReportRaw
+		if err = json.Unmarshal(body, &report); err != nil {
+			return publiciq.ReportRaw{}, fmt.Errorf("could not parse %s report of application '%s': %w", stage, appID, err)
+		}
+		report.ReportInfo = info
+
+		return report, nil
+	}
+
+	return publiciq.ReportRaw{}, fmt.Errorf("%s report of application '%s': %w", stage, appID, ErrNotFound)
+}
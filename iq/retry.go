@@ -0,0 +1,122 @@
+package privateiq
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy describes how requests which failed transiently are attempted again.
+// Connection failures and 429, 502, 503 and 504 responses are considered transient
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a request is sent, including the first one. Less than 2 disables retries
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, which doubles with each subsequent retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between retries. Zero means no cap
+	MaxBackoff time.Duration
+	// Jitter randomly varies each wait by up to the given fraction of it, i.e. 0.2 is ±20%
+	Jitter float64
+	// RetryNonIdempotent allows retrying methods such as POST, which IQ may have already acted upon
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy is a reasonable policy for a busy IQ server
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     30 * time.Second,
+	Jitter:         0.2,
+}
+
+// WithRetry sets the policy used to retry requests which failed transiently
+func WithRetry(policy RetryPolicy) Option {
+	return func(iq *privateiq) {
+		iq.retry = policy
+	}
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func isTransientStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryable reports whether the policy allows sending the request again after the given attempt
+func (p RetryPolicy) retryable(req *http.Request, attempt int) bool {
+	if attempt >= p.MaxAttempts {
+		return false
+	}
+	if !p.RetryNonIdempotent && !isIdempotent(req.Method) {
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// backoff returns how long to wait before the given retry, preferring the server's Retry-After if it sent one
+func (p RetryPolicy) backoff(retry int, resp *http.Response) time.Duration {
+	if wait, ok := retryAfter(resp); ok {
+		return wait
+	}
+
+	wait := float64(p.InitialBackoff) * math.Pow(2, float64(retry-1))
+	if p.MaxBackoff > 0 && wait > float64(p.MaxBackoff) {
+		wait = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		wait += wait * p.Jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(wait)
+}
+
+// retryAfter parses the Retry-After header which is either a number of seconds or an HTTP date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	header := resp.Header.Get("Retry-After")
+	if header == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(header); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(header); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// sleep waits for the given duration unless the context is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
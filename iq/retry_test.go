@@ -0,0 +1,93 @@
+package privateiq
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+func TestRetry(t *testing.T) {
+	var calls int32
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path[1:] == restSessionPrivate {
+			return
+		}
+		if atomic.AddInt32(&calls, 1)%3 != 0 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body := new(bytes.Buffer)
+		body.ReadFrom(r.Body)
+		fmt.Fprint(w, body.String())
+	}))
+	defer mock.Close()
+
+	pub, err := publiciq.New(mock.URL, "dummy_user", "dummy_pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+
+	t.Run("idempotent", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		body, _, err := FromPublic(pub, WithRetry(policy)).Put("rest/dummy", bytes.NewBufferString("payload"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != "payload" {
+			t.Errorf("retried request sent %q, want %q", body, "payload")
+		}
+		if calls != 3 {
+			t.Errorf("sent %d requests, want 3", calls)
+		}
+	})
+
+	t.Run("non-idempotent", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		_, _, err := FromPublic(pub, WithRetry(policy)).Post("rest/dummy", nil)
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("Post() error = %v, want a 503", err)
+		}
+		if calls != 1 {
+			t.Errorf("sent %d requests, want 1", calls)
+		}
+	})
+
+	t.Run("opted-in", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		optIn := policy
+		optIn.RetryNonIdempotent = true
+		if _, _, err := FromPublic(pub, WithRetry(optIn)).Post("rest/dummy", nil); err != nil {
+			t.Fatal(err)
+		}
+		if calls != 3 {
+			t.Errorf("sent %d requests, want 3", calls)
+		}
+	})
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second, Jitter: 0.5}
+
+	for retry, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second} {
+		got := policy.backoff(retry, nil)
+		if got < want/2 || got > want*3/2 {
+			t.Errorf("backoff(%d) = %s, want %s±50%%", retry, got, want)
+		}
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"7"}}}
+	if got := policy.backoff(1, resp); got != 7*time.Second {
+		t.Errorf("backoff() = %s, want the 7s from Retry-After", got)
+	}
+}
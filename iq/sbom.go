@@ -0,0 +1,287 @@
+package privateiq
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+// SBOMFormat is the format of a software bill of materials
+type SBOMFormat string
+
+// The SBOM formats understood by ParseSBOM
+const (
+	CycloneDXJSON SBOMFormat = "CycloneDX JSON"
+	CycloneDXXML  SBOMFormat = "CycloneDX XML"
+	SPDXJSON      SBOMFormat = "SPDX JSON"
+	SPDXTagValue  SBOMFormat = "SPDX tag-value"
+)
+
+// ErrUnknownSBOMFormat is returned when parsing a document which is neither CycloneDX nor SPDX
+var ErrUnknownSBOMFormat = errors.New("unknown SBOM format")
+
+// SBOMEntry is a component listed by an SBOM
+type SBOMEntry struct {
+	// Ref is the bom-ref of a CycloneDX component, or the SPDXID of an SPDX package
+	Ref        string
+	Name       string
+	Version    string
+	PackageURL string
+	SHA1       string
+}
+
+// component returns how IQ identifies the entry, by its package URL and SHA-1 hash, if it has either
+func (e SBOMEntry) component() (publiciq.Component, bool) {
+	c := publiciq.Component{PackageURL: e.PackageURL, Hash: strings.ToLower(e.SHA1)}
+	return c, c.PackageURL != "" || c.Hash != ""
+}
+
+// SBOM is a software bill of materials
+type SBOM struct {
+	Format  SBOMFormat
+	Entries []SBOMEntry
+}
+
+// ParseSBOM parses a CycloneDX document, in JSON or XML, or an SPDX document, in JSON or tag-value.
+// Nested CycloneDX components are listed after their parent
+func ParseSBOM(r io.Reader) (*SBOM, error) {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("could not read SBOM: %w", err)
+	}
+
+	trimmed := bytes.TrimSpace(buf)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		var doc struct {
+			BOMFormat   string `json:"bomFormat"`
+			SPDXVersion string `json:"spdxVersion"`
+		}
+		if err := json.Unmarshal(trimmed, &doc); err != nil {
+			return nil, fmt.Errorf("could not parse SBOM: %w", err)
+		}
+		switch {
+		case doc.BOMFormat == "CycloneDX":
+			return parseCycloneDXJSON(trimmed)
+		case doc.SPDXVersion != "":
+			return parseSPDXJSON(trimmed)
+		}
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return parseCycloneDXXML(trimmed)
+	case bytes.HasPrefix(trimmed, []byte("SPDXVersion:")):
+		return parseSPDXTagValue(trimmed)
+	}
+
+	return nil, ErrUnknownSBOMFormat
+}
+
+type cycloneDXComponent struct {
+	Ref     string `json:"bom-ref" xml:"bom-ref,attr"`
+	Name    string `json:"name" xml:"name"`
+	Version string `json:"version" xml:"version"`
+	PURL    string `json:"purl" xml:"purl"`
+	Hashes  []struct {
+		Alg     string `json:"alg" xml:"alg,attr"`
+		Content string `json:"content" xml:",chardata"`
+	} `json:"hashes" xml:"hashes>hash"`
+	Components []cycloneDXComponent `json:"components" xml:"components>component"`
+}
+
+func (c cycloneDXComponent) entries() []SBOMEntry {
+	e := SBOMEntry{Ref: c.Ref, Name: c.Name, Version: c.Version, PackageURL: strings.TrimSpace(c.PURL)}
+	for _, h := range c.Hashes {
+		if h.Alg == "SHA-1" {
+			e.SHA1 = strings.TrimSpace(h.Content)
+		}
+	}
+
+	entries := []SBOMEntry{e}
+	for _, n := range c.Components {
+		entries = append(entries, n.entries()...)
+	}
+	return entries
+}
+
+func cycloneDXEntries(format SBOMFormat, components []cycloneDXComponent) *SBOM {
+	sbom := &SBOM{Format: format, Entries: make([]SBOMEntry, 0, len(components))}
+	for _, c := range components {
+		sbom.Entries = append(sbom.Entries, c.entries()...)
+	}
+	return sbom
+}
+
+func parseCycloneDXJSON(buf []byte) (*SBOM, error) {
+	var doc struct {
+		Components []cycloneDXComponent `json:"components"`
+	}
+	if err := json.Unmarshal(buf, &doc); err != nil {
+		return nil, fmt.Errorf("could not parse CycloneDX document: %w", err)
+	}
+	return cycloneDXEntries(CycloneDXJSON, doc.Components), nil
+}
+
+func parseCycloneDXXML(buf []byte) (*SBOM, error) {
+	var doc struct {
+		XMLName    xml.Name
+		Components []cycloneDXComponent `xml:"components>component"`
+	}
+	if err := xml.Unmarshal(buf, &doc); err != nil {
+		return nil, fmt.Errorf("could not parse CycloneDX document: %w", err)
+	}
+	if doc.XMLName.Local != "bom" || !strings.HasPrefix(doc.XMLName.Space, "http://cyclonedx.org/schema/bom/") {
+		return nil, ErrUnknownSBOMFormat
+	}
+	return cycloneDXEntries(CycloneDXXML, doc.Components), nil
+}
+
+func parseSPDXJSON(buf []byte) (*SBOM, error) {
+	var doc struct {
+		Packages []struct {
+			ID        string `json:"SPDXID"`
+			Name      string `json:"name"`
+			Version   string `json:"versionInfo"`
+			Checksums []struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"checksumValue"`
+			} `json:"checksums"`
+			ExternalRefs []struct {
+				Type    string `json:"referenceType"`
+				Locator string `json:"referenceLocator"`
+			} `json:"externalRefs"`
+		} `json:"packages"`
+	}
+	if err := json.Unmarshal(buf, &doc); err != nil {
+		return nil, fmt.Errorf("could not parse SPDX document: %w", err)
+	}
+
+	sbom := &SBOM{Format: SPDXJSON, Entries: make([]SBOMEntry, 0, len(doc.Packages))}
+	for _, p := range doc.Packages {
+		e := SBOMEntry{Ref: p.ID, Name: p.Name, Version: p.Version}
+		for _, c := range p.Checksums {
+			if c.Algorithm == "SHA1" {
+				e.SHA1 = c.Value
+			}
+		}
+		for _, r := range p.ExternalRefs {
+			if r.Type == "purl" {
+				e.PackageURL = r.Locator
+			}
+		}
+		sbom.Entries = append(sbom.Entries, e)
+	}
+
+	return sbom, nil
+}
+
+func parseSPDXTagValue(buf []byte) (*SBOM, error) {
+	sbom := &SBOM{Format: SPDXTagValue, Entries: make([]SBOMEntry, 0)}
+	var pkg *SBOMEntry
+	var inText bool
+
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Multi-line values are wrapped in <text> tags
+		if inText {
+			inText = !strings.Contains(line, "</text>")
+			continue
+		}
+		if strings.Contains(line, "<text>") && !strings.Contains(line, "</text>") {
+			inText = true
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		tag, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+
+		switch tag {
+		case "PackageName":
+			sbom.Entries = append(sbom.Entries, SBOMEntry{Name: value})
+			pkg = &sbom.Entries[len(sbom.Entries)-1]
+		// Files and snippets also have an SPDXID, which ends the package
+		case "FileName", "SnippetSPDXID":
+			pkg = nil
+		}
+		if pkg == nil {
+			continue
+		}
+
+		switch tag {
+		case "SPDXID":
+			pkg.Ref = value
+		case "PackageVersion":
+			pkg.Version = value
+		case "PackageChecksum":
+			if checksum := strings.SplitN(value, ":", 2); len(checksum) == 2 && strings.TrimSpace(checksum[0]) == "SHA1" {
+				pkg.SHA1 = strings.TrimSpace(checksum[1])
+			}
+		case "ExternalRef":
+			if ref := strings.Fields(value); len(ref) == 3 && ref[1] == "purl" {
+				pkg.PackageURL = ref[2]
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not parse SPDX document: %w", err)
+	}
+
+	return sbom, nil
+}
+
+// SBOMEvaluation is the evaluation of the components of an SBOM
+type SBOMEvaluation struct {
+	Format     SBOMFormat
+	Evaluation *publiciq.Evaluation
+	// Unmapped lists the entries which have neither a package URL nor a SHA-1 hash, and were not evaluated
+	Unmapped []SBOMEntry
+}
+
+// EvaluateSBOM evaluates the components listed by a CycloneDX or SPDX document using Root Organization only.
+// See ParseSBOM
+func EvaluateSBOM(iq publiciq.IQ, sbom io.Reader) (*SBOMEvaluation, error) {
+	return EvaluateSBOMContext(context.Background(), iq, sbom)
+}
+
+// EvaluateSBOMContext evaluates the components listed by a CycloneDX or SPDX document using Root Organization only.
+// See ParseSBOM
+func EvaluateSBOMContext(ctx context.Context, iq publiciq.IQ, sbom io.Reader) (*SBOMEvaluation, error) {
+	doc, err := ParseSBOM(sbom)
+	if err != nil {
+		return nil, err
+	}
+
+	eval := &SBOMEvaluation{Format: doc.Format, Unmapped: make([]SBOMEntry, 0)}
+	components := make([]publiciq.Component, 0, len(doc.Entries))
+	seen := make(map[string]bool)
+	for _, e := range doc.Entries {
+		c, ok := e.component()
+		switch {
+		case !ok:
+			eval.Unmapped = append(eval.Unmapped, e)
+		case !seen[c.PackageURL+" "+c.Hash]:
+			seen[c.PackageURL+" "+c.Hash] = true
+			components = append(components, c)
+		}
+	}
+
+	if len(components) == 0 {
+		eval.Evaluation = &publiciq.Evaluation{Results: make([]publiciq.ComponentEvaluationResult, 0)}
+		return eval, nil
+	}
+
+	eval.Evaluation, err = EvaluateComponentsWithRootOrgContext(ctx, iq, components)
+	return eval, err
+}
@@ -0,0 +1,138 @@
+package privateiq
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hokiegeek/gonexus-private/iq/privateiqtest"
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+const (
+	guavaPURL  = "pkg:maven/com.google.guava/guava@30.0-jre?type=jar"
+	guavaSHA1  = "8ddbc8769f73309fe09b54c5951163f10b0d89fa"
+	lodashPURL = "pkg:npm/lodash@4.17.20"
+)
+
+var sbomDocuments = map[SBOMFormat]string{
+	CycloneDXJSON: `{
+  "bomFormat": "CycloneDX",
+  "specVersion": "1.4",
+  "components": [
+    {"bom-ref": "guava", "name": "guava", "version": "30.0-jre", "purl": "` + guavaPURL + `",
+     "hashes": [{"alg": "SHA-1", "content": "` + guavaSHA1 + `"}],
+     "components": [{"bom-ref": "lodash", "name": "lodash", "version": "4.17.20", "purl": "` + lodashPURL + `"}]},
+    {"bom-ref": "internal", "name": "internal", "version": "1.0.0"}
+  ]
+}`,
+	CycloneDXXML: `<?xml version="1.0" encoding="UTF-8"?>
+<bom xmlns="http://cyclonedx.org/schema/bom/1.4" version="1">
+  <components>
+    <component type="library" bom-ref="guava">
+      <name>guava</name>
+      <version>30.0-jre</version>
+      <hashes><hash alg="SHA-1">` + guavaSHA1 + `</hash></hashes>
+      <purl>` + guavaPURL + `</purl>
+      <components>
+        <component type="library" bom-ref="lodash">
+          <name>lodash</name>
+          <version>4.17.20</version>
+          <purl>` + lodashPURL + `</purl>
+        </component>
+      </components>
+    </component>
+    <component type="library" bom-ref="internal">
+      <name>internal</name>
+      <version>1.0.0</version>
+    </component>
+  </components>
+</bom>`,
+	SPDXJSON: `{
+  "spdxVersion": "SPDX-2.3",
+  "packages": [
+    {"SPDXID": "guava", "name": "guava", "versionInfo": "30.0-jre",
+     "checksums": [{"algorithm": "SHA1", "checksumValue": "` + guavaSHA1 + `"}],
+     "externalRefs": [{"referenceCategory": "PACKAGE-MANAGER", "referenceType": "purl", "referenceLocator": "` + guavaPURL + `"}]},
+    {"SPDXID": "lodash", "name": "lodash", "versionInfo": "4.17.20",
+     "externalRefs": [{"referenceCategory": "PACKAGE-MANAGER", "referenceType": "purl", "referenceLocator": "` + lodashPURL + `"}]},
+    {"SPDXID": "internal", "name": "internal", "versionInfo": "1.0.0"}
+  ]
+}`,
+	SPDXTagValue: `SPDXVersion: SPDX-2.3
+DataLicense: CC0-1.0
+DocumentComment: <text>Generated
+by hand: for testing</text>
+
+PackageName: guava
+SPDXID: guava
+PackageVersion: 30.0-jre
+PackageChecksum: SHA1: ` + guavaSHA1 + `
+ExternalRef: PACKAGE-MANAGER purl ` + guavaPURL + `
+
+PackageName: lodash
+SPDXID: lodash
+PackageVersion: 4.17.20
+ExternalRef: PACKAGE-MANAGER purl ` + lodashPURL + `
+
+FileName: ./README.md
+SPDXID: readme
+
+PackageName: internal
+SPDXID: internal
+PackageVersion: 1.0.0
+`,
+}
+
+func TestParseSBOM(t *testing.T) {
+	want := []SBOMEntry{
+		{Ref: "guava", Name: "guava", Version: "30.0-jre", PackageURL: guavaPURL, SHA1: guavaSHA1},
+		{Ref: "lodash", Name: "lodash", Version: "4.17.20", PackageURL: lodashPURL},
+		{Ref: "internal", Name: "internal", Version: "1.0.0"},
+	}
+
+	for format, doc := range sbomDocuments {
+		sbom, err := ParseSBOM(strings.NewReader(doc))
+		if err != nil {
+			t.Errorf("%s: %v", format, err)
+			continue
+		}
+		if sbom.Format != format {
+			t.Errorf("%s: detected as %s", format, sbom.Format)
+		}
+		if len(sbom.Entries) != len(want) {
+			t.Errorf("%s: got entries %v", format, sbom.Entries)
+			continue
+		}
+		for i, e := range sbom.Entries {
+			if e != want[i] {
+				t.Errorf("%s: got entry %v, want %v", format, e, want[i])
+			}
+		}
+	}
+
+	if _, err := ParseSBOM(strings.NewReader(`{"name": "package.json"}`)); !errors.Is(err, ErrUnknownSBOMFormat) {
+		t.Errorf("parsing an unknown format returned %v, want ErrUnknownSBOMFormat", err)
+	}
+}
+
+func TestEvaluateSBOM(t *testing.T) {
+	evaluationPollInterval = time.Millisecond
+
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	server.AddKnownComponent(privateiqtest.KnownComponent{Component: publiciq.Component{Hash: guavaSHA1, PackageURL: guavaPURL}})
+
+	eval, err := EvaluateSBOM(server.IQ(), strings.NewReader(sbomDocuments[CycloneDXJSON]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(eval.Unmapped) != 1 || eval.Unmapped[0].Name != "internal" {
+		t.Errorf("unexpected unmapped entries: %v", eval.Unmapped)
+	}
+	if results := eval.Evaluation.Results; len(results) != 2 || results[0].MatchState != "exact" || results[1].MatchState != "unknown" {
+		t.Errorf("unexpected evaluation results: %v", results)
+	}
+}
@@ -0,0 +1,89 @@
+package privateiq
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"time"
+
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+const restSupportZip = "rest/support?includeConfig=%t&includeLogs=%t&includeMetrics=%t&includeSysInfo=%t&noLimit=%t"
+
+// SupportZipOptions selects what a support zip contains, as the checkboxes of the IQ UI do
+type SupportZipOptions struct {
+	Logs              bool
+	Configuration     bool
+	SystemInformation bool
+	Metrics           bool
+	// LimitFileSizes truncates the larger files, such as the logs, to keep the archive small
+	LimitFileSizes bool
+	// Progress, if set, is called as the archive is written with the bytes written so far
+	// and the size of the archive, which is -1 when IQ does not tell it
+	Progress func(written, total int64)
+}
+
+// DefaultSupportZipOptions includes everything in the support zip, without limiting the size of its files
+var DefaultSupportZipOptions = SupportZipOptions{
+	Logs:              true,
+	Configuration:     true,
+	SystemInformation: true,
+	Metrics:           true,
+}
+
+// progressWriter reports the bytes written through it
+type progressWriter struct {
+	w        io.Writer
+	written  int64
+	total    int64
+	progress func(written, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	p.progress(p.written, p.total)
+	return n, err
+}
+
+// supportZipName returns the name IQ gave to the support zip, or one following its naming if it did not
+func supportZipName(contentDisposition string) string {
+	if _, params, err := mime.ParseMediaType(contentDisposition); err == nil && params["filename"] != "" {
+		return params["filename"]
+	}
+	return fmt.Sprintf("support-%s.zip", time.Now().Format("20060102-150405"))
+}
+
+// WriteSupportZip generates a support zip with the given contents and streams it to the writer, returning its name
+func WriteSupportZip(iq publiciq.IQ, w io.Writer, opts SupportZipOptions) (string, error) {
+	return WriteSupportZipContext(context.Background(), iq, w, opts)
+}
+
+// WriteSupportZipContext generates a support zip with the given contents and streams it to the writer, returning its name.
+// The download is bounded by the context rather than the time limit set by WithTimeout, as large archives take a while
+func WriteSupportZipContext(ctx context.Context, iq publiciq.IQ, w io.Writer, opts SupportZipOptions) (string, error) {
+	piq := fromPublicContext(ctx, iq)
+
+	endpoint, err := piq.endpoint("GetSupportZip", opts.Configuration, opts.Logs, opts.Metrics, opts.SystemInformation, !opts.LimitFileSizes)
+	if err != nil {
+		return "", err
+	}
+
+	_, resp, err := piq.stream().Get(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("error retrieving support zip: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if opts.Progress != nil {
+		w = &progressWriter{w: w, total: resp.ContentLength, progress: opts.Progress}
+	}
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return "", fmt.Errorf("error downloading support zip: %w", err)
+	}
+
+	return supportZipName(resp.Header.Get("Content-Disposition")), nil
+}
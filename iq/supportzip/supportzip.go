@@ -0,0 +1,371 @@
+// Package supportzip inspects IQ support zips and produces redacted copies of them,
+// so that they can be shared without disclosing hostnames, usernames, LDAP details or secrets.
+package supportzip
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	privateiq "github.com/hokiegeek/gonexus-private/iq"
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+// Kind is the kind of content of an entry of the support zip
+type Kind string
+
+// The kinds of entries of a support zip, as selected when generating it
+const (
+	KindLog     Kind = "log"
+	KindConfig  Kind = "config"
+	KindSysInfo Kind = "sysinfo"
+	KindMetrics Kind = "metrics"
+	KindOther   Kind = "other"
+)
+
+// Entry is a file of the support zip
+type Entry struct {
+	Name string
+	Kind Kind
+	Size int64
+}
+
+func kindOf(name string) Kind {
+	dir := strings.ToLower(strings.SplitN(name, "/", 2)[0])
+	ext := strings.ToLower(path.Ext(name))
+	switch {
+	case strings.HasPrefix(dir, "log") || ext == ".log":
+		return KindLog
+	case strings.HasPrefix(dir, "conf") || ext == ".yml" || ext == ".yaml" || ext == ".properties":
+		return KindConfig
+	case strings.HasPrefix(dir, "sysinfo"):
+		return KindSysInfo
+	case strings.HasPrefix(dir, "metrics"):
+		return KindMetrics
+	}
+	return KindOther
+}
+
+// Archive is an opened support zip
+type Archive struct {
+	zr      *zip.Reader
+	entries []Entry
+}
+
+// Open opens the support zip of the given size read from r
+func Open(r io.ReaderAt, size int64) (*Archive, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("could not open support zip: %w", err)
+	}
+
+	a := &Archive{zr: zr}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		a.entries = append(a.entries, Entry{Name: f.Name, Kind: kindOf(f.Name), Size: int64(f.UncompressedSize64)})
+	}
+
+	return a, nil
+}
+
+// OpenBytes opens a support zip held in memory, such as the one returned by privateiq.GetSupportZip
+func OpenBytes(buf []byte) (*Archive, error) {
+	return Open(bytes.NewReader(buf), int64(len(buf)))
+}
+
+// Download generates a support zip with the given contents and opens it, returning it along with its name
+func Download(ctx context.Context, iq publiciq.IQ, opts privateiq.SupportZipOptions) (*Archive, string, error) {
+	var buf bytes.Buffer
+	name, err := privateiq.WriteSupportZipContext(ctx, iq, &buf, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	a, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		return nil, "", err
+	}
+
+	return a, name, nil
+}
+
+// Entries returns the files of the support zip, only those of the given kinds if any are given
+func (a *Archive) Entries(kinds ...Kind) []Entry {
+	entries := make([]Entry, 0, len(a.entries))
+	for _, e := range a.entries {
+		if len(kinds) == 0 || hasKind(kinds, e.Kind) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+func hasKind(kinds []Kind, k Kind) bool {
+	for _, kind := range kinds {
+		if kind == k {
+			return true
+		}
+	}
+	return false
+}
+
+// Read returns the content of the named entry
+func (a *Archive) Read(name string) ([]byte, error) {
+	for _, f := range a.zr.File {
+		if f.Name == name {
+			return readFile(f)
+		}
+	}
+	return nil, fmt.Errorf("support zip has no entry '%s'", name)
+}
+
+func readFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("could not open entry '%s': %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	buf, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("could not read entry '%s': %w", f.Name, err)
+	}
+	return buf, nil
+}
+
+// Rule replaces what matches its pattern, expanding $1 and the like as regexp.ReplaceAllString does
+type Rule struct {
+	Name        string
+	Pattern     *regexp.Regexp
+	Replacement string
+
+	// word is set by Literal, whose occurrences are searched by hand so that adjacent ones are all found
+	word string
+	// subdomains also redacts the word when it ends a longer hostname, as a domain does
+	subdomains bool
+}
+
+// Literal returns a rule which redacts every occurrence of the given word, such as a known secret.
+// Only whole occurrences are redacted, not those within a longer word or hostname
+func Literal(name, word string) Rule {
+	return Rule{
+		Name:        name,
+		Pattern:     regexp.MustCompile(regexp.QuoteMeta(word)),
+		Replacement: "REDACTED-" + strings.ToUpper(name),
+		word:        word,
+	}
+}
+
+// Domain returns a rule which redacts every occurrence of the given hostname, including within its subdomains
+// such as ldap.iq.example.com or node-iq.example.com
+func Domain(name, host string) Rule {
+	r := Literal(name, host)
+	r.subdomains = true
+	return r
+}
+
+// isWordByte reports whether the byte continues a word, as \w does
+func isWordByte(b byte) bool {
+	return b == '_' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+// replaceWord replaces the whole occurrences of the word, returning how many there were.
+// Unlike a regular expression, checking the characters around an occurrence does not consume them
+func replaceWord(text, word, replacement string, subdomains bool) (string, int) {
+	if word == "" {
+		return text, 0
+	}
+
+	var b strings.Builder
+	var n, last int
+	for i := 0; i <= len(text)-len(word); {
+		j := strings.Index(text[i:], word)
+		if j < 0 {
+			break
+		}
+		start, end := i+j, i+j+len(word)
+
+		whole := (start == 0 || !isWordByte(text[start-1]) && (subdomains || text[start-1] != '.' && text[start-1] != '-')) &&
+			(end == len(text) || !isWordByte(text[end]) && text[end] != '-')
+		if !whole {
+			i = start + 1
+			continue
+		}
+
+		b.WriteString(text[last:start])
+		b.WriteString(replacement)
+		last, i = end, end
+		n++
+	}
+	if n == 0 {
+		return text, 0
+	}
+	b.WriteString(text[last:])
+
+	return b.String(), n
+}
+
+// DefaultRules redact the usual personal and infrastructure details found in a support zip
+var DefaultRules = []Rule{
+	{
+		Name:        "secret",
+		Pattern:     regexp.MustCompile(`(?i)("?[\w.-]*(?:password|passcode|secret|token)[\w.-]*"?\s*[:=]\s*)("[^"]*"|[^\s,}]+)`),
+		Replacement: `${1}"REDACTED"`,
+	},
+	{
+		Name:        "ldap",
+		Pattern:     regexp.MustCompile(`(?i)\b(?:cn|ou|uid|dc)=[^,\s"]+(?:\s*,\s*(?:cn|ou|uid|dc|o|c)=[^,\s"]+)*`),
+		Replacement: "REDACTED-DN",
+	},
+	{
+		// The whole authority goes, including any username and password before the host
+		Name:        "url",
+		Pattern:     regexp.MustCompile(`(?i)\b(https?|ldaps?)://(?:[^/\s"'@]*@)?[^/\s:"'@]+`),
+		Replacement: "${1}://REDACTED-HOST",
+	},
+	{
+		Name:        "hostname",
+		Pattern:     regexp.MustCompile(`(?i)((?:^|[\s{,"])(?:hostname|host|fqdn)"?\s*[:=]\s*)("[^"]*"|[^\s,}]+)`),
+		Replacement: `${1}"REDACTED-HOST"`,
+	},
+	{
+		Name:        "email",
+		Pattern:     regexp.MustCompile(`\b[\w.+-]+@[\w-]+(?:\.[\w-]+)+\b`),
+		Replacement: "REDACTED-EMAIL",
+	},
+	{
+		Name:        "ip",
+		Pattern:     regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`),
+		Replacement: "REDACTED-IP",
+	},
+}
+
+// ConnectionRules returns rules redacting the hostname, username and password the given instance connects with
+func ConnectionRules(iq publiciq.IQ) []Rule {
+	info := iq.Info()
+
+	rules := make([]Rule, 0, 3)
+	if u, err := url.Parse(info.Host); err == nil && u.Hostname() != "" {
+		rules = append(rules, Domain("host", u.Hostname()))
+	}
+	if info.Password != "" {
+		rules = append(rules, Literal("password", info.Password))
+	}
+	if info.Username != "" {
+		rules = append(rules, Literal("username", info.Username))
+	}
+	return rules
+}
+
+// Summary tells what was removed from a support zip, without disclosing it
+type Summary struct {
+	// Redactions counts the redactions made in each entry, by rule
+	Redactions map[string]map[string]int
+	// Unscanned lists the binary entries, which were copied unchanged
+	Unscanned []string
+}
+
+// Total returns the number of redactions made by the given rule, or by all of them if empty
+func (s Summary) Total(rule string) int {
+	var total int
+	for _, byRule := range s.Redactions {
+		for r, n := range byRule {
+			if rule == "" || r == rule {
+				total += n
+			}
+		}
+	}
+	return total
+}
+
+func (s Summary) String() string {
+	names := make([]string, 0, len(s.Redactions))
+	for name := range s.Redactions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		rules := make([]string, 0, len(s.Redactions[name]))
+		for r, n := range s.Redactions[name] {
+			rules = append(rules, fmt.Sprintf("%s=%d", r, n))
+		}
+		sort.Strings(rules)
+		fmt.Fprintf(&b, "%s: %s\n", name, strings.Join(rules, ", "))
+	}
+	for _, name := range s.Unscanned {
+		fmt.Fprintf(&b, "%s: not scanned\n", name)
+	}
+	return b.String()
+}
+
+// redact applies the rules, in order, to the text
+func redact(text string, rules []Rule) (string, map[string]int) {
+	counts := make(map[string]int)
+	for _, r := range rules {
+		var n int
+		if r.word != "" {
+			text, n = replaceWord(text, r.word, r.Replacement, r.subdomains)
+		} else if n = len(r.Pattern.FindAllStringIndex(text, -1)); n > 0 {
+			text = r.Pattern.ReplaceAllString(text, r.Replacement)
+		}
+		if n > 0 {
+			counts[r.Name] += n
+		}
+	}
+	return text, counts
+}
+
+// Redact writes a copy of the support zip to w with the given rules applied, in order, to every text entry
+func (a *Archive) Redact(w io.Writer, rules []Rule) (Summary, error) {
+	summary := Summary{Redactions: make(map[string]map[string]int)}
+
+	zw := zip.NewWriter(w)
+	for _, f := range a.zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		content, err := readFile(f)
+		if err != nil {
+			return summary, err
+		}
+
+		if utf8.Valid(content) {
+			text, counts := redact(string(content), rules)
+			content = []byte(text)
+			if len(counts) > 0 {
+				summary.Redactions[f.Name] = counts
+			}
+		} else {
+			summary.Unscanned = append(summary.Unscanned, f.Name)
+		}
+
+		header := f.FileHeader
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: header.Name, Method: header.Method, Modified: header.Modified})
+		if err != nil {
+			return summary, fmt.Errorf("could not write entry '%s': %w", f.Name, err)
+		}
+		if _, err := fw.Write(content); err != nil {
+			return summary, fmt.Errorf("could not write entry '%s': %w", f.Name, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return summary, fmt.Errorf("could not write support zip: %w", err)
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,133 @@
+package supportzip
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	privateiq "github.com/hokiegeek/gonexus-private/iq"
+	"github.com/hokiegeek/gonexus-private/iq/privateiqtest"
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+func TestEntries(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	archive, name, err := Download(context.Background(), server.IQ(), privateiq.DefaultSupportZipOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "support-20200101-000000.zip" {
+		t.Errorf("got support zip named %q", name)
+	}
+
+	for kind, want := range map[Kind]int{KindLog: 1, KindConfig: 2, KindSysInfo: 1, KindMetrics: 1, KindOther: 0} {
+		if got := len(archive.Entries(kind)); got != want {
+			t.Errorf("got %d %s entries, want %d", got, kind, want)
+		}
+	}
+	if got := len(archive.Entries()); got != 5 {
+		t.Errorf("got %d entries, want 5", got)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	buf, _, err := privateiq.GetSupportZip(server.IQ())
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, err := OpenBytes(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	iq, err := publiciq.New("http://iq.example.com:8070", privateiqtest.Username, privateiqtest.Password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var redacted bytes.Buffer
+	summary, err := archive.Redact(&redacted, append(ConnectionRules(iq), DefaultRules...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	copied, err := OpenBytes(redacted.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(copied.Entries()) != len(archive.Entries()) {
+		t.Errorf("the redacted copy has %d entries, want %d", len(copied.Entries()), len(archive.Entries()))
+	}
+
+	for _, e := range copied.Entries() {
+		content, err := copied.Read(e.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, secret := range []string{"iq.example.com", "ldap.example.com", "dc=example", "s3cr3t", " admin "} {
+			if strings.Contains(string(content), secret) {
+				t.Errorf("%s still contains %q: %s", e.Name, secret, content)
+			}
+		}
+	}
+
+	for _, rule := range []string{"host", "username", "ldap", "secret", "hostname"} {
+		if summary.Total(rule) == 0 {
+			t.Errorf("nothing was redacted by the %s rule: %s", rule, summary)
+		}
+	}
+	if strings.Contains(summary.String(), "s3cr3t") {
+		t.Error("the summary discloses what was removed")
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	tests := []struct {
+		in, want string
+		n        int
+	}{
+		{"users: admin,admin by admin admin", "users: REDACTED-USERNAME,REDACTED-USERNAME by REDACTED-USERNAME REDACTED-USERNAME", 4},
+		{"admin", "REDACTED-USERNAME", 1},
+		{"administrator sysadmin admin-console admin.example.com", "administrator sysadmin admin-console REDACTED-USERNAME.example.com", 1},
+		{"host.admin admin_x", "host.admin admin_x", 0},
+	}
+	for _, tt := range tests {
+		got, counts := redact(tt.in, []Rule{Literal("username", "admin")})
+		if got != tt.want || counts["username"] != tt.n {
+			t.Errorf("redact(%q) = %q, %d redactions, want %q, %d", tt.in, got, counts["username"], tt.want, tt.n)
+		}
+	}
+}
+
+func TestDomain(t *testing.T) {
+	in := "iq.example.com ldap.iq.example.com node-iq.example.com myiq.example.com iq.example.community"
+	want := "REDACTED-HOST ldap.REDACTED-HOST node-REDACTED-HOST myiq.example.com iq.example.community"
+	if got, counts := redact(in, []Rule{Domain("host", "iq.example.com")}); got != want || counts["host"] != 3 {
+		t.Errorf("redact(%q) = %q, %d redactions, want %q", in, got, counts["host"], want)
+	}
+}
+
+func TestURLRule(t *testing.T) {
+	var url Rule
+	for _, r := range DefaultRules {
+		if r.Name == "url" {
+			url = r
+		}
+	}
+
+	for in, want := range map[string]string{
+		"proxy: https://bob:pw@proxyhost:3128/": "proxy: https://REDACTED-HOST:3128/",
+		"ldaps://admin@ldap:636/dc=example":     "ldaps://REDACTED-HOST:636/dc=example",
+		"see http://iq.example.com/assets":      "see http://REDACTED-HOST/assets",
+	} {
+		if got, _ := redact(in, []Rule{url}); got != want {
+			t.Errorf("redact(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
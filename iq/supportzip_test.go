@@ -0,0 +1,130 @@
+package privateiq
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hokiegeek/gonexus-private/iq/privateiqtest"
+)
+
+func TestWriteSupportZip(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	var written, total int64
+	opts := SupportZipOptions{
+		Logs: true,
+		Progress: func(w, t int64) {
+			written, total = w, t
+		},
+	}
+
+	// A single request at once makes sure that streaming gives back its slot once done
+	iq := FromPublic(server.IQ(), WithConcurrency(1))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for i := 0; i < 2; i++ {
+		var buf bytes.Buffer
+		name, err := WriteSupportZipContext(ctx, iq, &buf, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name != "support-20200101-000000.zip" {
+			t.Errorf("got support zip named %q", name)
+		}
+
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatalf("support zip is not a valid archive: %v", err)
+		}
+		if len(zr.File) != 1 || zr.File[0].Name != "log/clm-server.log" {
+			t.Errorf("support zip does not only contain the logs: %v", zr.File)
+		}
+
+		if written != int64(buf.Len()) || total != int64(buf.Len()) {
+			t.Errorf("progress reported %d of %d bytes, want %d", written, total, buf.Len())
+		}
+	}
+}
+
+func TestSupportZipWithoutFilename(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+	server.SetSupportZip("", []byte("PK"))
+
+	buf, name, err := GetSupportZip(server.IQ())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "PK" {
+		t.Errorf("unexpected support zip content %q", buf)
+	}
+	if !regexp.MustCompile(`^support-\d{8}-\d{6}\.zip$`).MatchString(name) {
+		t.Errorf("unexpected fallback name %q", name)
+	}
+}
+
+// slowBody is a transport which delays reading the body of support zips, unless their request is canceled first
+type slowBody struct {
+	delay time.Duration
+}
+
+func (s slowBody) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil || req.URL.Path != "/"+restSupportZip[:strings.Index(restSupportZip, "?")] {
+		return resp, err
+	}
+	body := resp.Body
+	resp.Body = readCloser{
+		Reader: readerFunc(func(p []byte) (int, error) {
+			select {
+			case <-time.After(s.delay):
+				return body.Read(p)
+			case <-req.Context().Done():
+				return 0, req.Context().Err()
+			}
+		}),
+		Closer: body,
+	}
+	return resp, nil
+}
+
+type readerFunc func([]byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) { return f(p) }
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func TestSupportZipOutlastsTimeout(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	iq := FromPublic(server.IQ(), WithTimeout(50*time.Millisecond), WithTransport(slowBody{100 * time.Millisecond}))
+
+	var buf bytes.Buffer
+	if _, err := WriteSupportZip(iq, &buf, SupportZipOptions{Logs: true}); err != nil {
+		t.Fatalf("the download was cut off: %v", err)
+	}
+	if _, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+		t.Errorf("support zip is not a valid archive: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := WriteSupportZipContext(ctx, iq, ioutil.Discard, SupportZipOptions{Logs: true}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WriteSupportZipContext() error = %v, want the context's", err)
+	}
+}
@@ -0,0 +1,191 @@
+package privateiq
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+// Older versions named temporary organizations with a random number, as given by rand.Int(), and their application after it.
+// Shorter numbers are left alone as they are more likely the name of a real organization, such as a year
+var legacyTempName = regexp.MustCompile(`^\d{15,}$`)
+
+// TempOrganization is an organization created for an ad-hoc evaluation, usually left behind because it was interrupted
+type TempOrganization struct {
+	ID   string
+	Name string
+	// CreatedAt is the time in its name. It is unset for the numeric names of older versions
+	CreatedAt    time.Time
+	Applications []publiciq.Application
+	// Foreign lists the applications and child organizations which an evaluation would not have created.
+	// Organizations holding any are never deleted
+	Foreign []string
+}
+
+// Age returns how long ago the organization was created, if its name tells
+func (o TempOrganization) Age() (time.Duration, bool) {
+	if o.CreatedAt.IsZero() {
+		return 0, false
+	}
+	return time.Since(o.CreatedAt), true
+}
+
+// stale reports whether the organization can be deleted once older than the given age.
+// The age of those named by older versions is unknown, and they are only considered older than any if told so
+func (o TempOrganization) stale(olderThan time.Duration, unknownAge bool) bool {
+	if len(o.Foreign) > 0 {
+		return false
+	}
+	age, ok := o.Age()
+	if !ok {
+		return unknownAge
+	}
+	return age > olderThan
+}
+
+// tempScheme matches the names given by tempName, capturing their timestamp
+func (iq privateiq) tempScheme() *regexp.Regexp {
+	prefix := iq.tempPrefix
+	if prefix == "" {
+		prefix = DefaultTempPrefix
+	}
+	return regexp.MustCompile(`^` + regexp.QuoteMeta(prefix) + `-(\d{8}-\d{6})-[0-9a-f]{4}$`)
+}
+
+// tempOrganization returns the temporary organization the node is, if its name follows the scheme of ad-hoc evaluations
+func tempOrganization(n *OrganizationNode, scheme *regexp.Regexp) (TempOrganization, bool) {
+	org := TempOrganization{ID: n.ID, Name: n.Name, Applications: n.Applications}
+
+	var appPublicID string
+	switch m := scheme.FindStringSubmatch(n.Name); {
+	case m != nil:
+		created, err := time.Parse(tempTimeFormat, m[1])
+		if err != nil {
+			return org, false
+		}
+		org.CreatedAt = created
+		appPublicID = n.Name
+	case legacyTempName.MatchString(n.Name):
+		appPublicID = n.Name + "_app"
+		if !hasApplication(n, appPublicID) {
+			return org, false
+		}
+	default:
+		return org, false
+	}
+
+	for _, a := range n.Applications {
+		if a.PublicID != appPublicID {
+			org.Foreign = append(org.Foreign, "application "+a.PublicID)
+		}
+	}
+	for _, c := range n.Children {
+		org.Foreign = append(org.Foreign, "organization "+c.Name)
+	}
+
+	return org, true
+}
+
+func hasApplication(n *OrganizationNode, publicID string) bool {
+	for _, a := range n.Applications {
+		if a.PublicID == publicID {
+			return true
+		}
+	}
+	return false
+}
+
+// FindTempOrganizations lists the organizations named like the temporary ones created for ad-hoc evaluations,
+// with the prefix set by WithTempPrefix, or the random numbers of older versions along with their application
+func FindTempOrganizations(iq publiciq.IQ) ([]TempOrganization, error) {
+	return FindTempOrganizationsContext(context.Background(), iq)
+}
+
+// FindTempOrganizationsContext lists the organizations named like the temporary ones created for ad-hoc evaluations,
+// with the prefix set by WithTempPrefix, or the random numbers of older versions along with their application
+func FindTempOrganizationsContext(ctx context.Context, iq publiciq.IQ) ([]TempOrganization, error) {
+	piq := fromPublicContext(ctx, iq)
+
+	tree, err := GetOrganizationTreeContext(ctx, piq)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := piq.tempScheme()
+	orgs := make([]TempOrganization, 0)
+	tree.Root.Walk(func(n *OrganizationNode) error {
+		if org, ok := tempOrganization(n, scheme); ok {
+			orgs = append(orgs, org)
+		}
+		return nil
+	})
+
+	return orgs, nil
+}
+
+// CollectTempOrganizations deletes the temporary organizations, and their applications, older than the given age.
+// Those named by older versions, whose age is unknown, are only deleted when unknownAge is set.
+// Those holding anything an evaluation would not have created never are.
+// The organizations deleted are returned, and only listed when dryRun is set
+func CollectTempOrganizations(iq publiciq.IQ, olderThan time.Duration, unknownAge, dryRun bool) ([]TempOrganization, error) {
+	return CollectTempOrganizationsContext(context.Background(), iq, olderThan, unknownAge, dryRun)
+}
+
+// CollectTempOrganizationsContext deletes the temporary organizations, and their applications, older than the given age.
+// Those named by older versions, whose age is unknown, are only deleted when unknownAge is set.
+// Those holding anything an evaluation would not have created never are.
+// The organizations deleted are returned, and only listed when dryRun is set
+func CollectTempOrganizationsContext(ctx context.Context, iq publiciq.IQ, olderThan time.Duration, unknownAge, dryRun bool) ([]TempOrganization, error) {
+	piq := fromPublicContext(ctx, iq)
+
+	found, err := FindTempOrganizationsContext(ctx, piq)
+	if err != nil {
+		return nil, err
+	}
+
+	stale := make([]TempOrganization, 0, len(found))
+	for _, o := range found {
+		if o.stale(olderThan, unknownAge) {
+			stale = append(stale, o)
+		}
+	}
+	if dryRun {
+		return stale, nil
+	}
+
+	deleted := make([]TempOrganization, 0, len(stale))
+	var failed int
+	var firstErr error
+	for _, o := range stale {
+		if err := ctx.Err(); err != nil {
+			return deleted, fmt.Errorf("collection stopped: %w", err)
+		}
+
+		if err := deleteTempOrganization(ctx, piq, o); err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		deleted = append(deleted, o)
+	}
+
+	if failed > 0 {
+		return deleted, fmt.Errorf("%d temporary organizations not deleted, the first because: %w", failed, firstErr)
+	}
+
+	return deleted, nil
+}
+
+func deleteTempOrganization(ctx context.Context, piq *privateiq, o TempOrganization) error {
+	for _, a := range o.Applications {
+		if err := deleteApplication(piq, a.ID); err != nil {
+			return err
+		}
+	}
+	return DeleteOrganizationContext(ctx, piq, o.ID)
+}
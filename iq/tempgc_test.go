@@ -0,0 +1,86 @@
+package privateiq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hokiegeek/gonexus-private/iq/privateiqtest"
+)
+
+func TestCollectTempOrganizations(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	legacy := server.AddOrganization("5577006791947779410", "")
+	server.AddApplication("5577006791947779410_app", "5577006791947779410_app", legacy.ID)
+	old := server.AddOrganization("privateiq-tmp-20200101-000000-abcd", "")
+	server.AddApplication(old.Name, old.Name, old.ID)
+	fresh := server.AddOrganization(FromPublic(server.IQ()).(*privateiq).tempName(), "")
+	server.AddApplication(fresh.Name, fresh.Name, fresh.ID)
+	foreign := server.AddOrganization("8674665223082153551", "")
+	server.AddApplication("8674665223082153551_app", "8674665223082153551_app", foreign.ID)
+	server.AddApplication("payments", "payments", foreign.ID)
+	server.AddOrganization("Payments", "")
+	// Numeric names are only those of older versions if long enough and holding the matching application
+	server.AddOrganization("2024", "")
+	server.AddOrganization("6129484611666145821", "")
+
+	found, err := FindTempOrganizations(server.IQ())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 4 {
+		t.Fatalf("found %d temporary organizations, want 4: %v", len(found), found)
+	}
+	for _, o := range found {
+		age, ok := o.Age()
+		switch o.ID {
+		case legacy.ID:
+			if ok {
+				t.Errorf("legacy organization has an age of %s", age)
+			}
+		case old.ID:
+			if !ok || age < 24*time.Hour {
+				t.Errorf("organization created in 2020 has an age of %s", age)
+			}
+		case foreign.ID:
+			if len(o.Foreign) != 1 {
+				t.Errorf("foreign contents are %v, want the payments application", o.Foreign)
+			}
+		}
+	}
+
+	// Organizations of unknown age are only collected when told so
+	stale, err := CollectTempOrganizations(server.IQ(), time.Hour, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stale) != 1 || stale[0].ID != old.ID {
+		t.Errorf("dry run selected %v, want the old organization", stale)
+	}
+
+	stale, err = CollectTempOrganizations(server.IQ(), time.Hour, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stale) != 2 || stale[0].ID != legacy.ID || stale[1].ID != old.ID {
+		t.Errorf("dry run selected %v, want the legacy and old organizations", stale)
+	}
+	if orgs := server.Organizations(); len(orgs) != 8 {
+		t.Errorf("dry run deleted organizations: %v", orgs)
+	}
+
+	deleted, err := CollectTempOrganizations(server.IQ(), time.Hour, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 2 {
+		t.Errorf("deleted %v, want the legacy and old organizations", deleted)
+	}
+	if orgs := server.Organizations(); len(orgs) != 6 {
+		t.Errorf("unexpected remaining organizations: %v", orgs)
+	}
+	if apps := server.Applications(); len(apps) != 3 {
+		t.Errorf("unexpected remaining applications: %v", apps)
+	}
+}
@@ -0,0 +1,231 @@
+package privateiq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+const restProductVersion = "rest/product/version"
+
+// ErrUnsupportedVersion is returned by functions whose private API is not served by the version of the IQ server
+var ErrUnsupportedVersion = fmt.Errorf("%w on this version", ErrUnsupported)
+
+// Version is the release of an IQ server, such as 1.85.0
+type Version struct {
+	Major, Minor, Patch int
+}
+
+// ParseVersion parses versions as reported by IQ, such as 1.85.0-01
+func ParseVersion(s string) (Version, error) {
+	var v Version
+
+	release := strings.SplitN(strings.TrimSpace(s), "-", 2)[0]
+	parts := strings.Split(release, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return v, fmt.Errorf("invalid IQ version '%s'", s)
+	}
+
+	nums := make([]int, 3)
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid IQ version '%s'", s)
+		}
+		nums[i] = n
+	}
+
+	return Version{nums[0], nums[1], nums[2]}, nil
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Less reports whether the version is older than the given one
+func (v Version) Less(o Version) bool {
+	if v.Major != o.Major {
+		return v.Major < o.Major
+	}
+	if v.Minor != o.Minor {
+		return v.Minor < o.Minor
+	}
+	return v.Patch < o.Patch
+}
+
+// IsZero reports whether the version is unset
+func (v Version) IsZero() bool {
+	return v == Version{}
+}
+
+// EndpointVariant is an endpoint of a private API, as a format string of its parameters,
+// along with the range of IQ versions which serve it
+type EndpointVariant struct {
+	Endpoint string
+	// MinVersion is the first version which serves the endpoint. Unset when it is served by every older version
+	MinVersion Version
+	// MaxVersion is the first version which no longer serves the endpoint. Unset when it is still served
+	MaxVersion Version
+}
+
+// Supports reports whether the given IQ version serves the endpoint
+func (e EndpointVariant) Supports(v Version) bool {
+	if !e.MinVersion.IsZero() && v.Less(e.MinVersion) {
+		return false
+	}
+	return e.MaxVersion.IsZero() || v.Less(e.MaxVersion)
+}
+
+func (e EndpointVariant) unbounded() bool {
+	return e.MinVersion.IsZero() && e.MaxVersion.IsZero()
+}
+
+// The first versions of IQ serving the private endpoints which did not exist in every release
+var (
+	waiversVersion  = Version{1, 47, 0}
+	firewallVersion = Version{1, 60, 0}
+	// firewallPagingVersion is the first to filter, sort and page the Firewall report, as FirewallQuery describes
+	firewallPagingVersion = Version{1, 99, 0}
+)
+
+// compatibility lists, for each function of the package, the variants of the private endpoint it uses.
+// The first variant which supports the version of the IQ server is used
+var compatibility = map[string][]EndpointVariant{
+	"DeleteOrganization":                      {{Endpoint: restOrganizationPrivate}},
+	"RenameOrganization":                      {{Endpoint: restOrganizationPrivate}},
+	"MoveOrganization":                        {{Endpoint: restOrganizationMove}},
+	"MoveApplication":                         {{Endpoint: restApplicationMove}},
+	"GetOrganizationTree":                     {{Endpoint: restOrganizationsPrivate}},
+	"GetFirewallState":                        {{Endpoint: restFirewallPrivate, MinVersion: firewallVersion}},
+	"GetSupportZip":                           {{Endpoint: restSupportZip}},
+	"CreateWebhook":                           {{Endpoint: restWebhooks}},
+	"GetWebhooks":                             {{Endpoint: restWebhooks}},
+	"GetAutomaticApplications":                {{Endpoint: restAutoApps}},
+	"EnableAutomaticApplications":             {{Endpoint: restAutoApps}},
+	"DisableAutomaticApplications":            {{Endpoint: restAutoApps}},
+	"EnableNotice":                            {{Endpoint: restSystemNotice}},
+	"DisableNotice":                           {{Endpoint: restSystemNotice}},
+	"EnableContinuousMonitoringApplication":   {{Endpoint: restMonitoringApp}},
+	"EnableContinuousMonitoringOrganization":  {{Endpoint: restMonitoringOrg}},
+	"DisableContinuousMonitoringApplication":  {{Endpoint: restMonitoringApp}},
+	"DisableContinuousMonitoringOrganization": {{Endpoint: restMonitoringOrg}},
+	"TriggerContinuousMonitoring":             {{Endpoint: restMonitoringTrigger}},
+	"InstallLicense":                          {{Endpoint: restLicense}},
+	"LicenseInfo":                             {{Endpoint: restLicense}},
+	"ReevaluateReportByID":                    {{Endpoint: restReportReevaluate}},
+	"GetAllComponentLabels":                   {{Endpoint: restLabelComponentOrg}},
+	"CreateComponentLabel":                    {{Endpoint: restLabelComponentOrg}},
+	"VulnerabilityInfoHTML":                   {{Endpoint: restVulnDetails}},
+	"ExportPolicies":                          {{Endpoint: restPolicyExportPrivate}},
+	"ImportPolicies":                          {{Endpoint: restPolicyImportPrivate}},
+	"WaiversByAppIDStage":                     {{Endpoint: restWaiversForApplication, MinVersion: waiversVersion}},
+	"QueryFirewallReport": {
+		{Endpoint: restFirewallPaged, MinVersion: firewallPagingVersion},
+		{Endpoint: restFirewallPrivate, MinVersion: firewallVersion, MaxVersion: firewallPagingVersion},
+	},
+}
+
+// WithEndpointVariants replaces the endpoint variants used by the named function of the package,
+// which allows using endpoints of IQ versions the package does not know about yet
+func WithEndpointVariants(function string, variants ...EndpointVariant) Option {
+	return func(iq *privateiq) {
+		overrides := make(map[string][]EndpointVariant, len(iq.variants)+1)
+		for k, v := range iq.variants {
+			overrides[k] = v
+		}
+		overrides[function] = variants
+		iq.variants = overrides
+	}
+}
+
+// serverVersion caches the version of the IQ server so that it is only detected once
+type serverVersion struct {
+	sync.Mutex
+	detected bool
+	version  Version
+}
+
+func (iq privateiq) detectVersion() (Version, error) {
+	iq.version.Lock()
+	defer iq.version.Unlock()
+	if iq.version.detected {
+		return iq.version.version, nil
+	}
+
+	body, _, err := iq.Get(restProductVersion)
+	if err != nil {
+		return Version{}, fmt.Errorf("could not retrieve IQ version: %w", err)
+	}
+
+	var resp struct {
+		Version string `json:"version"`
+	}
+	if err = json.Unmarshal(body, &resp); err != nil {
+		return Version{}, fmt.Errorf("could not parse IQ version: %w", err)
+	}
+
+	v, err := ParseVersion(resp.Version)
+	if err != nil {
+		return Version{}, err
+	}
+
+	iq.version.version = v
+	iq.version.detected = true
+
+	return v, nil
+}
+
+// variant returns the variant of the endpoint used by the named function which the IQ server serves.
+// If it serves none, an error matching ErrUnsupportedVersion is returned
+func (iq privateiq) variant(function string) (EndpointVariant, error) {
+	variants, ok := iq.variants[function]
+	if !ok {
+		variants = compatibility[function]
+	}
+	if len(variants) == 0 {
+		return EndpointVariant{}, fmt.Errorf("%s has no known endpoint: %w", function, ErrUnsupported)
+	}
+
+	// Spare detecting the version when it would not make a difference
+	if len(variants) == 1 && variants[0].unbounded() {
+		return variants[0], nil
+	}
+
+	version, err := iq.detectVersion()
+	if err != nil {
+		return EndpointVariant{}, err
+	}
+
+	for _, v := range variants {
+		if v.Supports(version) {
+			return v, nil
+		}
+	}
+
+	return EndpointVariant{}, fmt.Errorf("%s is not available on IQ %s: %w", function, version, ErrUnsupportedVersion)
+}
+
+// endpoint returns the endpoint used by the named function on the IQ server, with the given parameters.
+// If the server does not serve any variant of it, an error matching ErrUnsupportedVersion is returned
+func (iq privateiq) endpoint(function string, args ...interface{}) (string, error) {
+	v, err := iq.variant(function)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(v.Endpoint, args...), nil
+}
+
+// ServerVersion returns the version of the IQ server. It is detected once per instance returned by FromPublic
+func ServerVersion(iq publiciq.IQ) (Version, error) {
+	return ServerVersionContext(context.Background(), iq)
+}
+
+// ServerVersionContext returns the version of the IQ server. It is detected once per instance returned by FromPublic
+func ServerVersionContext(ctx context.Context, iq publiciq.IQ) (Version, error) {
+	return fromPublicContext(ctx, iq).detectVersion()
+}
@@ -0,0 +1,153 @@
+package privateiq
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hokiegeek/gonexus-private/iq/privateiqtest"
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Version
+		wantErr bool
+	}{
+		{"1.85.0-01", Version{1, 85, 0}, false},
+		{"1.100.2", Version{1, 100, 2}, false},
+		{"1.69", Version{1, 69, 0}, false},
+		{"release", Version{}, true},
+		{"1.x.0", Version{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseVersion(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseVersion(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ParseVersion(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEndpointVariantSupports(t *testing.T) {
+	variant := EndpointVariant{MinVersion: Version{1, 70, 0}, MaxVersion: Version{1, 90, 0}}
+
+	for v, want := range map[Version]bool{
+		{1, 69, 9}: false,
+		{1, 70, 0}: true,
+		{1, 89, 0}: true,
+		{1, 90, 0}: false,
+	} {
+		if got := variant.Supports(v); got != want {
+			t.Errorf("Supports(%s) = %v, want %v", v, got, want)
+		}
+	}
+}
+
+func TestVersionGating(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+	server.SetVersion("1.85.0-01")
+	server.SetFirewallComponents("maven-central", []privateiqtest.FirewallComponent{{Hash: "1234"}})
+
+	iq := FromPublic(server.IQ(), WithEndpointVariants("GetFirewallState",
+		EndpointVariant{Endpoint: "rest/firewall/%s/report", MinVersion: Version{1, 90, 0}},
+		EndpointVariant{Endpoint: restFirewallPrivate, MinVersion: Version{1, 60, 0}, MaxVersion: Version{1, 90, 0}},
+	))
+
+	version, err := ServerVersion(iq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != (Version{1, 85, 0}) {
+		t.Errorf("detected version %s, want 1.85.0", version)
+	}
+
+	if _, err := GetFirewallState(iq, "maven-central"); err != nil {
+		t.Errorf("the variant served by the version was not used: %v", err)
+	}
+
+	old := FromPublic(iq, WithEndpointVariants("GetFirewallState",
+		EndpointVariant{Endpoint: restFirewallPrivate, MaxVersion: Version{1, 80, 0}},
+	))
+	_, err = GetFirewallState(old, "maven-central")
+	if !errors.Is(err, ErrUnsupportedVersion) || !errors.Is(err, ErrUnsupported) {
+		t.Errorf("GetFirewallState() error = %v, want ErrUnsupportedVersion", err)
+	}
+
+	var detections int
+	for _, r := range server.Requests() {
+		if r == "GET "+restProductVersion {
+			detections++
+		}
+	}
+	if detections != 1 {
+		t.Errorf("detected the version %d times, want once", detections)
+	}
+}
+
+func TestUnboundedEndpointSkipsDetection(t *testing.T) {
+	server := privateiqtest.NewServer()
+	defer server.Close()
+
+	if _, err := LicenseInfo(server.IQ()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range server.Requests() {
+		if r == "GET "+restProductVersion {
+			t.Error("detected the version although no variant depends on it")
+		}
+	}
+}
+
+func TestBuiltinVersionGating(t *testing.T) {
+	tests := []struct {
+		version                string
+		waivers, firewallState bool
+	}{
+		{privateiqtest.DefaultVersion, true, true},
+		{"1.60.0-01", true, true},
+		{"1.55.0-02", true, false},
+		{"1.46.1-01", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			server := waiversTestServer()
+			defer server.Close()
+			server.SetVersion(tt.version)
+			server.SetFirewallComponents("maven-central", []privateiqtest.FirewallComponent{{Hash: "1234"}})
+
+			requested := func(req string) bool {
+				for _, r := range server.Requests() {
+					if r == req {
+						return true
+					}
+				}
+				return false
+			}
+
+			_, err := WaiversByAppIDStage(server.IQ(), "app1", publiciq.StageBuild)
+			switch {
+			case tt.waivers && err != nil:
+				t.Errorf("WaiversByAppIDStage() error = %v", err)
+			case tt.waivers && !requested("GET rest/policyWaiver/application/app1/component/app1hash1"):
+				t.Errorf("WaiversByAppIDStage() did not use %s: %v", restWaiversForApplication, server.Requests())
+			case !tt.waivers && !errors.Is(err, ErrUnsupportedVersion):
+				t.Errorf("WaiversByAppIDStage() error = %v, want ErrUnsupportedVersion", err)
+			}
+
+			_, err = GetFirewallState(server.IQ(), "maven-central")
+			switch {
+			case tt.firewallState && err != nil:
+				t.Errorf("GetFirewallState() error = %v", err)
+			case tt.firewallState && !requested("GET rest/repositories/maven-central/report/details"):
+				t.Errorf("GetFirewallState() did not use %s: %v", restFirewallPrivate, server.Requests())
+			case !tt.firewallState && !errors.Is(err, ErrUnsupportedVersion):
+				t.Errorf("GetFirewallState() error = %v, want ErrUnsupportedVersion", err)
+			}
+		})
+	}
+}
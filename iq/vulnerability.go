@@ -1,17 +1,14 @@
 package privateiq
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"strings"
 
 	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
 )
 
-const (
-	restVulnDetailsCve      = "rest/vulnerability/details/cve/%s"
-	restVulnDetailsSonatype = "rest/vulnerability/details/sonatype/%s"
-)
+const restVulnDetails = "rest/vulnerability/details/%s/%s"
 
 type iqIssueInfo struct {
 	RefID       string `json:"refId"`
@@ -21,15 +18,25 @@ type iqIssueInfo struct {
 
 // VulnerabilityInfoHTML returns an HTML representation of the Vulnerability Info panel of a given vulnerability
 func VulnerabilityInfoHTML(iq publiciq.IQ, vulnerabilityID string) (info string, err error) {
+	return VulnerabilityInfoHTMLContext(context.Background(), iq, vulnerabilityID)
+}
+
+// VulnerabilityInfoHTMLContext returns an HTML representation of the Vulnerability Info panel of a given vulnerability
+func VulnerabilityInfoHTMLContext(ctx context.Context, iq publiciq.IQ, vulnerabilityID string) (info string, err error) {
+	piq := fromPublicContext(ctx, iq)
+
 	var endpoint string
 	vulnID := strings.ToLower(vulnerabilityID)
 	if strings.HasPrefix(vulnID, "cve") {
-		endpoint = fmt.Sprintf(restVulnDetailsCve, vulnerabilityID)
+		endpoint, err = piq.endpoint("VulnerabilityInfoHTML", "cve", vulnerabilityID)
 	} else {
-		endpoint = fmt.Sprintf(restVulnDetailsSonatype, vulnID)
+		endpoint, err = piq.endpoint("VulnerabilityInfoHTML", "sonatype", vulnID)
+	}
+	if err != nil {
+		return
 	}
 
-	body, _, err := FromPublic(iq).Get(endpoint)
+	body, _, err := piq.Get(endpoint)
 	if err != nil {
 		return
 	}
@@ -1,7 +1,9 @@
 package privateiq
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 
@@ -77,8 +79,13 @@ func waiversFromJSON(jsonWaivers []waiverJSON, c publiciq.Component) []Waiver {
 	return waivers
 }
 
-func getWaiversByComponentHash(iq publiciq.IQ, appID, hash string) ([]waiversByOwner, error) {
-	body, _, err := FromPublic(iq).Get(fmt.Sprintf(restWaiversForApplication, appID, hash))
+func getWaiversByComponentHash(iq *privateiq, appID, hash string) ([]waiversByOwner, error) {
+	endpoint, err := iq.endpoint("WaiversByAppIDStage", appID, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	body, _, err := iq.Get(endpoint)
 	if err != nil {
 		return nil, err
 	}
@@ -89,69 +96,175 @@ func getWaiversByComponentHash(iq publiciq.IQ, appID, hash string) ([]waiversByO
 	return waivers.WaiversByOwner, err
 }
 
-// WaiversByAppIDStage returns the waivers associated with an application
+// WaiversError is the failure to list some of the waivers of a sweep, whose other waivers are returned all the same
+type WaiversError struct {
+	// Errs holds the failure of each application, stage or component, in the order they occurred
+	Errs []error
+}
+
+func (e *WaiversError) Error() string {
+	return fmt.Sprintf("%d waiver lookups failed, the first because: %v", len(e.Errs), e.Errs[0])
+}
+
+// Unwrap returns the first failure
+func (e *WaiversError) Unwrap() error {
+	return e.Errs[0]
+}
+
+// waiversError returns the failures as a *WaiversError, or nil if there are none
+func waiversError(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &WaiversError{Errs: errs}
+}
+
+// waiverErrs returns the failures the error holds, those of a *WaiversError being flattened
+func waiverErrs(err error) []error {
+	var werr *WaiversError
+	if errors.As(err, &werr) {
+		return werr.Errs
+	}
+	return []error{err}
+}
+
+// WaiversByAppIDStage returns the waivers associated with an application.
+// An error matching ErrNotFound is returned if it has no report for the stage.
+// The waivers of the components which could be looked up are returned along with a *WaiversError for the others
 func WaiversByAppIDStage(iq publiciq.IQ, appID, stage string) ([]Waiver, error) {
-	report, err := publiciq.GetRawReportByAppID(iq, appID, stage)
+	return WaiversByAppIDStageContext(context.Background(), iq, appID, stage)
+}
+
+// WaiversByAppIDStageContext returns the waivers associated with an application.
+// An error matching ErrNotFound is returned if it has no report for the stage.
+// The waivers of the components which could be looked up are returned along with a *WaiversError for the others
+func WaiversByAppIDStageContext(ctx context.Context, iq publiciq.IQ, appID, stage string) ([]Waiver, error) {
+	piq := fromPublicContext(ctx, iq)
+
+	report, err := getRawReportByAppID(piq, appID, stage)
 	if err != nil {
 		return nil, err
 	}
 
 	waivers := make([]Waiver, 0)
 
+	var failed []error
 	for _, c := range report.Components {
-		byOwner, _ := getWaiversByComponentHash(iq, appID, c.Hash)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		byOwner, err := getWaiversByComponentHash(piq, appID, c.Hash)
+		// No other component would do better on a server without the API
+		if errors.Is(err, ErrUnsupported) {
+			return nil, err
+		}
+		if err != nil {
+			failed = append(failed, fmt.Errorf("could not retrieve waivers of component '%s' in application '%s': %w", c.Hash, appID, err))
+			continue
+		}
 		for _, o := range byOwner {
 			waivers = append(waivers, waiversFromJSON(o.Waivers, c.Component)...)
 		}
 	}
 
-	return waivers, nil
+	return waivers, waiversError(failed)
 }
 
-// WaiversByAppID returns the waivers associated with an application
+// WaiversByAppID returns the waivers associated with an application, at the stages it has a report for.
+// What could be looked up is returned along with a *WaiversError for the rest
 func WaiversByAppID(iq publiciq.IQ, appID string) ([]Waiver, error) {
+	return WaiversByAppIDContext(context.Background(), iq, appID)
+}
+
+// WaiversByAppIDContext returns the waivers associated with an application, at the stages it has a report for.
+// What could be looked up is returned along with a *WaiversError for the rest
+func WaiversByAppIDContext(ctx context.Context, iq publiciq.IQ, appID string) ([]Waiver, error) {
+	piq := fromPublicContext(ctx, iq)
+
 	waivers := make([]Waiver, 0)
 
+	var failed []error
 	stages := []string{publiciq.StageBuild, publiciq.StageStageRelease, publiciq.StageRelease, publiciq.StageOperate}
 	for _, s := range stages {
-		stageWaivers, _ := WaiversByAppIDStage(iq, appID, s)
+		stageWaivers, err := WaiversByAppIDStageContext(ctx, piq, appID, s)
 		waivers = append(waivers, stageWaivers...)
+
+		var werr *WaiversError
+		switch {
+		case err == nil:
+		case errors.As(err, &werr):
+			failed = append(failed, werr.Errs...)
+		// Only the stages the application was evaluated at have a report
+		case errors.Is(err, ErrNotFound):
+		case errors.Is(err, ErrUnsupported), ctx.Err() != nil:
+			return nil, err
+		default:
+			failed = append(failed, err)
+		}
 	}
 
-	return waivers, nil
+	return waivers, waiversError(failed)
 }
 
 // Waivers returns the waivers for all applications in IQ
 func Waivers(iq publiciq.IQ) ([]Waiver, error) {
-	apps, err := publiciq.GetAllApplications(iq)
+	return WaiversContext(context.Background(), iq)
+}
+
+// WaiversContext returns the waivers for all applications in IQ.
+// If the context is done, the workers stop and the context's error is returned.
+// Otherwise, what could be looked up is returned along with a *WaiversError for the rest
+func WaiversContext(ctx context.Context, iq publiciq.IQ) ([]Waiver, error) {
+	// Wrap once so that every worker shares the same private session
+	piq := fromPublicContext(ctx, iq)
+
+	apps, err := getAllApplications(piq)
 	if err != nil {
 		return nil, err
 	}
 
 	waivers := make([]Waiver, 0)
 
+	var failed []error
 	var mu sync.Mutex
 	var wg sync.WaitGroup
-	appIDs := make(chan string, 20)
-	for w := 1; w <= 20; w++ {
+	// There is no point in more workers than the requests the limiter lets through at once
+	workers := piq.limiter.workers(20)
+	appIDs := make(chan string, workers)
+	for w := 1; w <= workers; w++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			for a := range appIDs {
-				appWaivers, _ := WaiversByAppID(iq, a)
+				if ctx.Err() != nil {
+					continue
+				}
+				appWaivers, err := WaiversByAppIDContext(ctx, piq, a)
 				mu.Lock()
 				waivers = append(waivers, appWaivers...)
+				if err != nil {
+					failed = append(failed, waiverErrs(err)...)
+				}
 				mu.Unlock()
 			}
 		}()
 	}
 
+feed:
 	for _, a := range apps {
-		appIDs <- a.PublicID
+		select {
+		case appIDs <- a.PublicID:
+		case <-ctx.Done():
+			break feed
+		}
 	}
 	close(appIDs)
 
 	wg.Wait()
 
-	return waivers, nil
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return waivers, waiversError(failed)
 }
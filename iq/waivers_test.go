@@ -0,0 +1,85 @@
+package privateiq
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/hokiegeek/gonexus-private/iq/privateiqtest"
+	publiciq "github.com/sonatype-nexus-community/gonexus/iq"
+)
+
+func waiversTestServer() *privateiqtest.Server {
+	server := privateiqtest.NewServer()
+
+	for _, id := range []string{"app1", "app2"} {
+		server.AddApplication(id, id, publiciq.RootOrganization)
+		server.AddReport(id, publiciq.StageBuild, publiciq.Component{Hash: id + "hash1"}, publiciq.Component{Hash: id + "hash2"})
+		server.AddWaiver(id, privateiqtest.Waiver{Hash: id + "hash2", PolicyID: "policy1", PolicyName: "Security-High"})
+	}
+
+	return server
+}
+
+func TestWaiversByAppIDStage(t *testing.T) {
+	server := waiversTestServer()
+	defer server.Close()
+
+	waivers, err := WaiversByAppIDStage(server.IQ(), "app1", publiciq.StageBuild)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(waivers) != 1 || waivers[0].Component.Hash != "app1hash2" || waivers[0].PolicyName != "Security-High" {
+		t.Errorf("unexpected waivers: %v", waivers)
+	}
+}
+
+func TestWaivers(t *testing.T) {
+	server := waiversTestServer()
+	defer server.Close()
+
+	waivers, err := Waivers(server.IQ())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(waivers) != 2 {
+		t.Errorf("got %d waivers, want 2: %v", len(waivers), waivers)
+	}
+}
+
+func TestWaiversErrors(t *testing.T) {
+	server := waiversTestServer()
+	defer server.Close()
+
+	if _, err := WaiversByAppIDStage(server.IQ(), "app1", publiciq.StageRelease); !errors.Is(err, ErrNotFound) {
+		t.Errorf("WaiversByAppIDStage() of a stage without report error = %v, want ErrNotFound", err)
+	}
+
+	// Stages without report are skipped, but no other error is
+	waivers, err := WaiversByAppID(server.IQ(), "app1")
+	if err != nil || len(waivers) != 1 {
+		t.Errorf("WaiversByAppID() = %v, %v", waivers, err)
+	}
+
+	broken := FromPublic(server.IQ(), WithTransport(failing{"GET rest/policyWaiver/"}))
+	var apiErr *APIError
+	if _, err := WaiversByAppID(broken, "app1"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("WaiversByAppID() error = %v, want the server error", err)
+	}
+	if waivers, err := Waivers(broken); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Waivers() = %v, %v, want the server error", waivers, err)
+	}
+
+	// A failing application does not lose the waivers of the others
+	partial := FromPublic(server.IQ(), WithTransport(failing{"GET rest/policyWaiver/application/app1/"}))
+	waivers, err = Waivers(partial)
+	var werr *WaiversError
+	if !errors.As(err, &werr) || len(werr.Errs) != 2 || !errors.As(err, &apiErr) {
+		t.Errorf("Waivers() error = %v, want the failures of both components of app1", err)
+	}
+	if len(waivers) != 1 || waivers[0].Component.Hash != "app2hash2" {
+		t.Errorf("Waivers() = %v, want those of app2", waivers)
+	}
+}